@@ -1,6 +1,7 @@
 package ninjarmm
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,7 +13,12 @@ import (
 //
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getActivities
 func GetActivityLog(options ActivityLogOptions) (activityLog ActivityLog, err error) {
-	err = request(http.MethodGet, "activities?"+options.queryString(), nil, &activityLog)
+	return getActivityLog(context.Background(), options)
+}
+
+// Internal context aware version of GetActivityLog
+func getActivityLog(ctx context.Context, options ActivityLogOptions) (activityLog ActivityLog, err error) {
+	err = requestContext(ctx, http.MethodGet, "activities?"+options.queryString(), nil, &activityLog)
 	return
 }
 
@@ -0,0 +1,401 @@
+package ninjarmm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Value written in place of redacted payload values
+const auditRedacted = "[REDACTED]"
+
+// Operation names of the mutating routes of the package, placeholders name
+// the targets of the request
+var auditOperations = map[string]string{
+	"POST organizations":                                                      "createOrganization",
+	"PATCH organization/{organizationId}":                                     "updateOrganization",
+	"PATCH organization/{organizationId}/custom-fields":                       "updateOrganizationCustomFields",
+	"PUT organization/{organizationId}/policies":                              "updateOrganizationPolicies",
+	"POST organization/{organizationId}/document/{documentId}":                "updateOrganizationDocument",
+	"POST organization/{organizationId}/locations":                            "createLocation",
+	"PATCH organization/{organizationId}/locations/{locationId}":              "updateLocation",
+	"PATCH organization/{organizationId}/location/{locationId}/custom-fields": "updateLocationCustomFields",
+	"PATCH device/{deviceId}":                                                 "updateDevice",
+	"PATCH device/{deviceId}/custom-fields":                                   "updateDeviceCustomFields",
+	"POST device/{deviceId}/owner/{ownerUid}":                                 "setDeviceOwner",
+	"DELETE device/{deviceId}/owner":                                          "removeDeviceOwner",
+	"POST device/{deviceId}/script/run":                                       "runDeviceScript",
+	"POST ticketing/ticket":                                                   "createTicket",
+	"PUT ticketing/ticket/{ticketId}":                                         "updateTicket",
+	"POST ticketing/contact/contacts":                                         "createContact",
+	"PUT ticketing/contact/contacts/{contactId}":                              "updateContact",
+	"DELETE ticketing/contact/contacts/{contactId}":                           "deleteContact",
+	"POST user/end-users":                                                     "createEndUser",
+	"PATCH user/end-user/{userId}":                                            "updateEndUser",
+	"DELETE user/end-user/{userId}":                                           "deleteEndUser",
+	"POST user/technicians":                                                   "inviteTechnician",
+	"PATCH user/technician/{userId}":                                          "updateTechnician",
+	"POST user/{userId}/resend-invite":                                        "resendInvitation",
+	"PATCH user/role/{roleId}/add-users":                                      "addUsersToRole",
+	"PATCH user/role/{roleId}/remove-users":                                   "removeUsersFromRole",
+}
+
+// Payload keys always redacted in audit entries (case insensitive)
+var defaultAuditRedactKeys = []string{"password", "secret", "token", "apiKey", "clientSecret", "credentials"}
+
+var (
+	// Audit configuration, see EnableAudit
+	audit   *auditConfig
+	auditMu sync.RWMutex
+)
+
+// Record every POST, PUT, PATCH and DELETE request made by the package
+// (including requests intercepted in dry-run mode) into an audit sink.
+//
+// Payload values of sensitive keys (see AuditOptions.RedactKeys) are
+// redacted, as well as secure (TEXT_ENCRYPTED) custom fields when their
+// definitions are set with UseCustomFieldDefinitions.
+//
+// Usage:
+//
+//	sink, err := ninjarmm.NewJSONLinesAuditSink("ninjarmm-audit.jsonl")
+//	if err != nil {
+//		panic(err)
+//	}
+//	defer sink.Close()
+//
+//	ninjarmm.EnableAudit(ninjarmm.AuditOptions{Sink: sink})
+//
+//	ctx := ninjarmm.WithAuditActor(context.Background(), "jdoe", "CHG-1234")
+//	err = ninjarmm.UpdateCustomFields(ctx, ninjarmm.DeviceNode(12), modify)
+func EnableAudit(options AuditOptions) {
+	config := &auditConfig{
+		AuditOptions: options,
+		redactKeys:   make(map[string]bool),
+	}
+
+	for _, key := range append(defaultAuditRedactKeys, options.RedactKeys...) {
+		config.redactKeys[strings.ToLower(key)] = true
+	}
+
+	if config.OnError == nil {
+		config.OnError = func(err error) {
+			log.Printf("[ninjarmm audit] %s", err)
+		}
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if options.Sink == nil {
+		audit = nil
+	} else {
+		audit = config
+	}
+}
+
+// Disable the audit of mutating requests.
+func DisableAudit() {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	audit = nil
+}
+
+// WithAuditActor returns a context carrying the actor and reason recorded in
+// the audit entries of the requests made with it.
+func WithAuditActor(ctx context.Context, actor, reason string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, auditActor{actor, reason})
+}
+
+// Record a mutating request in the audit sink, if enabled
+func recordAudit(ctx context.Context, method, path string, payload []byte, dryRun bool, requestErr error) {
+	if isReadOnlyRequest(method, path) {
+		return
+	}
+
+	auditMu.RLock()
+	config := audit
+	auditMu.RUnlock()
+
+	if config == nil {
+		return
+	}
+
+	operation, route, targets := auditOperation(method, path)
+
+	entry := AuditEntry{
+		Time:      time.Now(),
+		Operation: operation,
+		Route:     route,
+		Method:    method,
+		Path:      path,
+		Targets:   targets,
+		Payload:   config.redact(payload, secureCustomFields(route)),
+		Outcome:   AuditOutcomeSuccess,
+		Actor:     config.DefaultActor,
+	}
+
+	if actor, ok := ctx.Value(auditActorKey{}).(auditActor); ok {
+		entry.Actor = actor.actor
+		entry.Reason = actor.reason
+	}
+
+	if dryRun {
+		entry.Outcome = AuditOutcomeDryRun
+	} else if requestErr != nil {
+		entry.Outcome = AuditOutcomeFailure
+		entry.Error = requestErr.Error()
+	}
+
+	if err := config.Sink.Record(entry); err != nil {
+		config.OnError(fmt.Errorf("error recording audit entry for %s: %w", operation, err))
+	}
+}
+
+// Find the operation name, the route (method and path template) and the
+// targets of a request, e.g. "updateLocationCustomFields" and
+// "PATCH organization/{organizationId}/location/{locationId}/custom-fields".
+// Requests outside of auditOperations are named by their route, numeric
+// path segments being their targets.
+func auditOperation(method, path string) (operation, route string, targets map[string]string) {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+
+	for template, name := range auditOperations {
+		templateMethod, templatePath, _ := strings.Cut(template, " ")
+		if templateMethod != method {
+			continue
+		}
+
+		if matched, ok := matchAuditRoute(strings.Split(templatePath, "/"), segments); ok {
+			return name, template, matched
+		}
+	}
+
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err != nil || i == 0 {
+			continue
+		}
+
+		name := auditTargetName(segments[i-1])
+		if targets == nil {
+			targets = make(map[string]string)
+		}
+		targets[name] = segment
+		segments[i] = "{" + name + "}"
+	}
+
+	route = method + " " + strings.Join(segments, "/")
+	return route, route, targets
+}
+
+// Match path segments against a route template, returns the values of its
+// placeholders
+func matchAuditRoute(template, segments []string) (targets map[string]string, ok bool) {
+	if len(template) != len(segments) {
+		return
+	}
+
+	for i, segment := range template {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			if targets == nil {
+				targets = make(map[string]string)
+			}
+			targets[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return targets, true
+}
+
+// Target name of the ID following a path segment, e.g. 'endUserId' after
+// "end-users" or 'policyId' after "policies"
+func auditTargetName(segment string) string {
+	words := strings.Split(segment, "-")
+	if singular, ok := endpointFamilies[words[len(words)-1]]; ok {
+		words[len(words)-1] = singular
+	}
+
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "") + "Id"
+}
+
+// Names of the secure (TEXT_ENCRYPTED) custom fields when the route sets
+// custom fields, according to the definitions set by
+// UseCustomFieldDefinitions
+func secureCustomFields(route string) (names map[string]bool) {
+	if !strings.HasSuffix(route, "/custom-fields") {
+		return
+	}
+
+	customFieldDefinitionsMu.RLock()
+	definitions := customFieldDefinitions
+	customFieldDefinitionsMu.RUnlock()
+
+	for _, definition := range definitions {
+		if definition.Type == CustomFieldTypeSecure {
+			if names == nil {
+				names = make(map[string]bool)
+			}
+			names[definition.Name] = true
+		}
+	}
+	return
+}
+
+// Redact sensitive values from a JSON payload, `secureFields` are top level
+// keys redacted whatever their name
+func (config *auditConfig) redact(payload []byte, secureFields map[string]bool) json.RawMessage {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return json.RawMessage(payload)
+	}
+
+	if object, ok := decoded.(map[string]interface{}); ok {
+		for key := range object {
+			if secureFields[key] {
+				object[key] = auditRedacted
+			}
+		}
+	}
+
+	redacted, err := json.Marshal(config.redactValue(decoded))
+	if err != nil {
+		return json.RawMessage(payload)
+	}
+
+	return json.RawMessage(redacted)
+}
+
+func (config *auditConfig) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if config.redactKeys[strings.ToLower(key)] {
+				v[key] = auditRedacted
+			} else {
+				v[key] = config.redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = config.redactValue(item)
+		}
+	}
+	return value
+}
+
+// Destination of the audit entries
+type AuditSink interface {
+	Record(entry AuditEntry) error
+}
+
+// Options for EnableAudit
+type AuditOptions struct {
+	// Destination of the audit entries, nil disables the audit
+	Sink AuditSink
+
+	// Additional payload keys whose values are redacted (case insensitive)
+	RedactKeys []string
+
+	// Actor recorded when the context has none (see WithAuditActor)
+	DefaultActor string
+
+	// Called when the sink fails, defaults to the standard logger
+	OnError func(err error)
+}
+
+type auditConfig struct {
+	AuditOptions
+	redactKeys map[string]bool
+}
+
+type auditActorKey struct{}
+
+type auditActor struct {
+	actor  string
+	reason string
+}
+
+// Record of a mutating request
+type AuditEntry struct {
+	Time      time.Time         `json:"time"`
+	Operation string            `json:"operation"` // Operation name, e.g. 'updateLocation'
+	Route     string            `json:"route"`     // Method and path template
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Targets   map[string]string `json:"targets,omitempty"` // Identifiers found in the path, e.g. 'organizationId' or 'ownerUid'
+	Payload   json.RawMessage   `json:"payload,omitempty"` // Redacted payload
+	Outcome   AuditOutcome      `json:"outcome"`
+	Error     string            `json:"error,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+}
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "SUCCESS"
+	AuditOutcomeFailure AuditOutcome = "FAILURE"
+	AuditOutcomeDryRun  AuditOutcome = "DRY_RUN"
+)
+
+// Append-only JSON lines audit file, one entry per line
+type JSONLinesAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewJSONLinesAuditSink opens (or creates) an append-only JSON lines audit
+// file.
+func NewJSONLinesAuditSink(path string) (sink *JSONLinesAuditSink, err error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		err = fmt.Errorf("error opening audit file: %w", err)
+		return
+	}
+
+	sink = &JSONLinesAuditSink{file: file}
+	return
+}
+
+// Record appends the entry to the file and syncs it to disk.
+func (sink *JSONLinesAuditSink) Record(entry AuditEntry) (err error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if _, err = sink.file.Write(append(line, '\n')); err != nil {
+		return
+	}
+
+	return sink.file.Sync()
+}
+
+// Close closes the audit file.
+func (sink *JSONLinesAuditSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.file.Close()
+}
@@ -0,0 +1,379 @@
+package ninjarmm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kind of value of each known board field, it restricts the operators
+var boardFieldKinds = map[BoardField]boardFieldKind{
+	BoardFieldID:               boardFieldNumber,
+	BoardFieldSummary:          boardFieldText,
+	BoardFieldDescription:      boardFieldText,
+	BoardFieldStatus:           boardFieldEnum,
+	BoardFieldPriority:         boardFieldEnum,
+	BoardFieldSeverity:         boardFieldEnum,
+	BoardFieldType:             boardFieldEnum,
+	BoardFieldOrganization:     boardFieldEnum,
+	BoardFieldLocation:         boardFieldEnum,
+	BoardFieldDevice:           boardFieldEnum,
+	BoardFieldRequester:        boardFieldEnum,
+	BoardFieldAssignedAppUser:  boardFieldEnum,
+	BoardFieldTicketForm:       boardFieldEnum,
+	BoardFieldTags:             boardFieldEnum,
+	BoardFieldCreateTime:       boardFieldDate,
+	BoardFieldLastUpdated:      boardFieldDate,
+	BoardFieldTotalTimeTracked: boardFieldNumber,
+}
+
+// Operators allowed for each kind of field
+var boardOperators = map[boardFieldKind][]BoardOperator{
+	boardFieldText:   {BoardOperatorEquals, BoardOperatorNotEquals, BoardOperatorContains, BoardOperatorNotContains, BoardOperatorPresent, BoardOperatorNotPresent},
+	boardFieldEnum:   {BoardOperatorIn, BoardOperatorNotIn, BoardOperatorPresent, BoardOperatorNotPresent},
+	boardFieldNumber: {BoardOperatorEquals, BoardOperatorNotEquals, BoardOperatorGreaterThan, BoardOperatorLessThan, BoardOperatorBetween},
+	boardFieldDate:   {BoardOperatorGreaterThan, BoardOperatorLessThan, BoardOperatorBetween},
+}
+
+// Build the filters and sorting of a board run, see ListTicketsByBoard.
+//
+// Usage:
+//
+//	query := ninjarmm.NewBoardQuery().
+//		StatusIn("NEW", "OPEN").
+//		PriorityIn(ninjarmm.PriorityHigh).
+//		CreatedBetween(time.Now().AddDate(0, 0, -7), time.Now()).
+//		SortBy(ninjarmm.BoardFieldCreateTime, ninjarmm.SortDescending)
+//
+//	tickets, err := query.Run(boardID)
+func NewBoardQuery() *BoardQuery {
+	return &BoardQuery{}
+}
+
+// Where adds a filter. Values are rendered as strings, time.Time and
+// ninjarmm.Time as unix seconds, and several values are joined with commas.
+func (query *BoardQuery) Where(field BoardField, operator BoardOperator, values ...any) *BoardQuery {
+	query.filters = append(query.filters, boardFilter{field: field, operator: operator, values: values})
+	return query
+}
+
+// StatusIn filters tickets by status names.
+func (query *BoardQuery) StatusIn(statuses ...string) *BoardQuery {
+	return query.Where(BoardFieldStatus, BoardOperatorIn, toAnySlice(statuses)...)
+}
+
+// PriorityIn filters tickets by priorities.
+func (query *BoardQuery) PriorityIn(priorities ...Priority) *BoardQuery {
+	return query.Where(BoardFieldPriority, BoardOperatorIn, toAnySlice(priorities)...)
+}
+
+// SeverityIn filters tickets by severities.
+func (query *BoardQuery) SeverityIn(severities ...Severity) *BoardQuery {
+	return query.Where(BoardFieldSeverity, BoardOperatorIn, toAnySlice(severities)...)
+}
+
+// TypeIn filters tickets by types.
+func (query *BoardQuery) TypeIn(types ...TicketType) *BoardQuery {
+	return query.Where(BoardFieldType, BoardOperatorIn, toAnySlice(types)...)
+}
+
+// OrganizationIn filters tickets by organization IDs.
+func (query *BoardQuery) OrganizationIn(organizationIDs ...int) *BoardQuery {
+	return query.Where(BoardFieldOrganization, BoardOperatorIn, toAnySlice(organizationIDs)...)
+}
+
+// AssignedTo filters tickets by assigned technician IDs.
+func (query *BoardQuery) AssignedTo(userIDs ...int) *BoardQuery {
+	return query.Where(BoardFieldAssignedAppUser, BoardOperatorIn, toAnySlice(userIDs)...)
+}
+
+// Unassigned keeps tickets without technician.
+func (query *BoardQuery) Unassigned() *BoardQuery {
+	return query.Where(BoardFieldAssignedAppUser, BoardOperatorNotPresent)
+}
+
+// TaggedWith filters tickets having one of the tags.
+func (query *BoardQuery) TaggedWith(tags ...string) *BoardQuery {
+	return query.Where(BoardFieldTags, BoardOperatorIn, toAnySlice(tags)...)
+}
+
+// SummaryContains filters tickets whose summary contains a text.
+func (query *BoardQuery) SummaryContains(text string) *BoardQuery {
+	return query.Where(BoardFieldSummary, BoardOperatorContains, text)
+}
+
+// CreatedBetween filters tickets created in a period.
+func (query *BoardQuery) CreatedBetween(from, to time.Time) *BoardQuery {
+	return query.Where(BoardFieldCreateTime, BoardOperatorBetween, from, to)
+}
+
+// CreatedAfter filters tickets created after a date.
+func (query *BoardQuery) CreatedAfter(after time.Time) *BoardQuery {
+	return query.Where(BoardFieldCreateTime, BoardOperatorGreaterThan, after)
+}
+
+// Search filters tickets with a full text search.
+func (query *BoardQuery) Search(text string) *BoardQuery {
+	query.options.SearchCriteria = text
+	return query
+}
+
+// SortBy adds a sort field.
+func (query *BoardQuery) SortBy(field BoardField, direction SortDirection) *BoardQuery {
+	query.options.SortBy = append(query.options.SortBy, SortBy{Field: string(field), Direction: string(direction)})
+	return query
+}
+
+// Columns sets the columns returned in addition to the board ones.
+func (query *BoardQuery) Columns(fields ...BoardField) *BoardQuery {
+	for _, field := range fields {
+		query.options.IncludeColumns = append(query.options.IncludeColumns, string(field))
+	}
+	return query
+}
+
+// PageSize sets the number of tickets returned.
+func (query *BoardQuery) PageSize(pageSize int) *BoardQuery {
+	query.options.PageSize = pageSize
+	return query
+}
+
+// After continues from the LastCursorID of the previous page metadata.
+func (query *BoardQuery) After(lastCursorID int) *BoardQuery {
+	query.options.LastCursorID = lastCursorID
+	return query
+}
+
+// Validate checks operators and values against the known fields and, when
+// `columns` are given (TicketingBoard.Columns or BoardMetadata.AllColumns),
+// that filtered and sorted fields are columns of the board. All problems
+// are returned joined.
+func (query *BoardQuery) Validate(columns ...string) error {
+	var errs []error
+
+	available := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		available[column] = true
+	}
+
+	checkColumn := func(field string) {
+		if len(columns) > 0 && !available[field] {
+			errs = append(errs, fmt.Errorf("field '%s' is not a board column", field))
+		}
+	}
+
+	for _, filter := range query.filters {
+		checkColumn(string(filter.field))
+		if err := filter.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, sort := range query.options.SortBy {
+		checkColumn(sort.Field)
+		if sort.Direction != string(SortAscending) && sort.Direction != string(SortDescending) {
+			errs = append(errs, fmt.Errorf("invalid sort direction '%s' for field '%s'", sort.Direction, sort.Field))
+		}
+	}
+
+	for _, column := range query.options.IncludeColumns {
+		checkColumn(column)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Options validates the query (without columns, see Validate) and renders
+// the options of ListTicketsByBoard.
+func (query *BoardQuery) Options() (options ListTicketsOptions, err error) {
+	err = query.Validate()
+	if err != nil {
+		return
+	}
+
+	options = query.options
+	options.Filters = make([]BoardCondition, len(query.filters))
+	for i, filter := range query.filters {
+		options.Filters[i] = filter.condition()
+	}
+	return
+}
+
+// Run the query on a board. The query is validated against the columns of
+// the board and the columns it adds (see Validate) before running.
+func (query *BoardQuery) Run(boardID int) (tickets BoardTickets, err error) {
+	boards, err := ListTicketingBoards()
+	if err != nil {
+		err = fmt.Errorf("error listing ticketing boards: %w", err)
+		return
+	}
+
+	var columns []string
+	found := false
+	for _, board := range boards {
+		if board.ID == boardID {
+			columns, found = board.Columns, true
+			break
+		}
+	}
+
+	if !found {
+		err = fmt.Errorf("ticketing board %d not found", boardID)
+		return
+	}
+
+	err = query.Validate(append(columns, query.options.IncludeColumns...)...)
+	if err != nil {
+		return
+	}
+
+	options, err := query.Options()
+	if err != nil {
+		return
+	}
+	return ListTicketsByBoard(boardID, options)
+}
+
+// Check the operator and the number of values of a filter
+func (filter boardFilter) validate() error {
+	kind, known := boardFieldKinds[filter.field]
+	if !known {
+		// Custom attributes and new fields are not checked
+		return nil
+	}
+
+	allowed := false
+	for _, operator := range boardOperators[kind] {
+		allowed = allowed || operator == filter.operator
+	}
+	if !allowed {
+		return fmt.Errorf("operator '%s' not allowed for field '%s'", filter.operator, filter.field)
+	}
+
+	switch filter.operator {
+	case BoardOperatorPresent, BoardOperatorNotPresent:
+		if len(filter.values) != 0 {
+			return fmt.Errorf("operator '%s' takes no value for field '%s'", filter.operator, filter.field)
+		}
+	case BoardOperatorBetween:
+		if len(filter.values) != 2 {
+			return fmt.Errorf("operator '%s' takes 2 values for field '%s'", filter.operator, filter.field)
+		}
+	case BoardOperatorIn, BoardOperatorNotIn:
+		if len(filter.values) == 0 {
+			return fmt.Errorf("operator '%s' takes at least 1 value for field '%s'", filter.operator, filter.field)
+		}
+	default:
+		if len(filter.values) != 1 {
+			return fmt.Errorf("operator '%s' takes 1 value for field '%s'", filter.operator, filter.field)
+		}
+	}
+
+	if kind == boardFieldDate {
+		for _, value := range filter.values {
+			switch value.(type) {
+			case time.Time, Time:
+			default:
+				return fmt.Errorf("field '%s' takes time.Time or ninjarmm.Time values", filter.field)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Render the filter as a board condition
+func (filter boardFilter) condition() BoardCondition {
+	values := make([]string, len(filter.values))
+	for i, value := range filter.values {
+		switch v := value.(type) {
+		case time.Time:
+			values[i] = fmt.Sprint(v.Unix())
+		case Time:
+			values[i] = fmt.Sprint(time.Time(v).Unix())
+		default:
+			values[i] = fmt.Sprint(v)
+		}
+	}
+
+	return BoardCondition{
+		Field:    string(filter.field),
+		Operator: string(filter.operator),
+		Value:    strings.Join(values, ","),
+	}
+}
+
+func toAnySlice[T any](values []T) []any {
+	converted := make([]any, len(values))
+	for i, value := range values {
+		converted[i] = value
+	}
+	return converted
+}
+
+// Filters and sorting of a board run, see NewBoardQuery
+type BoardQuery struct {
+	filters []boardFilter
+	options ListTicketsOptions
+}
+
+type boardFilter struct {
+	field    BoardField
+	operator BoardOperator
+	values   []any
+}
+
+type boardFieldKind int
+
+const (
+	boardFieldText boardFieldKind = iota
+	boardFieldEnum
+	boardFieldNumber
+	boardFieldDate
+)
+
+type BoardField string
+
+const (
+	BoardFieldID               BoardField = "id"
+	BoardFieldSummary          BoardField = "summary"
+	BoardFieldDescription      BoardField = "description"
+	BoardFieldStatus           BoardField = "status"
+	BoardFieldPriority         BoardField = "priority"
+	BoardFieldSeverity         BoardField = "severity"
+	BoardFieldType             BoardField = "type"
+	BoardFieldOrganization     BoardField = "organization"
+	BoardFieldLocation         BoardField = "location"
+	BoardFieldDevice           BoardField = "device"
+	BoardFieldRequester        BoardField = "requester"
+	BoardFieldAssignedAppUser  BoardField = "assignedAppUser"
+	BoardFieldTicketForm       BoardField = "ticketForm"
+	BoardFieldTags             BoardField = "tags"
+	BoardFieldCreateTime       BoardField = "createTime"
+	BoardFieldLastUpdated      BoardField = "lastUpdated"
+	BoardFieldTotalTimeTracked BoardField = "totalTimeTracked"
+)
+
+type BoardOperator string
+
+const (
+	BoardOperatorEquals      BoardOperator = "eq"
+	BoardOperatorNotEquals   BoardOperator = "neq"
+	BoardOperatorIn          BoardOperator = "in"
+	BoardOperatorNotIn       BoardOperator = "not_in"
+	BoardOperatorContains    BoardOperator = "contains"
+	BoardOperatorNotContains BoardOperator = "not_contains"
+	BoardOperatorGreaterThan BoardOperator = "greater_than"
+	BoardOperatorLessThan    BoardOperator = "less_than"
+	BoardOperatorBetween     BoardOperator = "between"
+	BoardOperatorPresent     BoardOperator = "present"
+	BoardOperatorNotPresent  BoardOperator = "not_present"
+)
+
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "ASC"
+	SortDescending SortDirection = "DESC"
+)
@@ -0,0 +1,294 @@
+package ninjarmm
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default number of devices processed in parallel by bulk operations
+const defaultBulkConcurrency = 4
+
+// Apply the same update to many devices with at most `concurrency`
+// parallel requests (default: 4).
+//
+// Each device gets its own result in the returned report, a failure on one
+// device does not stop the others.
+func BulkUpdateDevices(deviceIDs []int, update DeviceUpdate, concurrency int) (report BulkDeviceReport) {
+	devices := make([]Device, len(deviceIDs))
+	for i, deviceID := range deviceIDs {
+		devices[i].ID = deviceID
+	}
+
+	return bulkDevices(context.Background(), devices, bulkOptions{concurrency: concurrency}, func(ctx context.Context, device Device) (result BulkDeviceResult) {
+		result.Err = UpdateDevice(device.ID, update)
+		return
+	})
+}
+
+// Move many devices to a location of their organization.
+//
+// See BulkUpdateDevices
+func MoveDevicesToLocation(deviceIDs []int, locationID int, concurrency int) (report BulkDeviceReport) {
+	update := DeviceUpdate{}
+	update.SetLocationID(locationID)
+	return BulkUpdateDevices(deviceIDs, update, concurrency)
+}
+
+// Assign a policy to many devices.
+//
+// See BulkUpdateDevices
+func MoveDevicesToPolicy(deviceIDs []int, policyID int, concurrency int) (report BulkDeviceReport) {
+	update := DeviceUpdate{}
+	update.SetPolicyID(policyID)
+	return BulkUpdateDevices(deviceIDs, update, concurrency)
+}
+
+// Set custom fields on all devices matching a device filter.
+//
+// Either a static `Patch` or a per-device `PatchFunc` must be given. The
+// returned error only reports failures listing the devices, per-device
+// failures are in the report.
+//
+// Usage:
+//
+//	report, err := ninjarmm.BulkSetDeviceCustomFields(ctx, ninjarmm.BulkCustomFieldsOptions{
+//		DeviceFilter: "org = 12",
+//		Patch:        ninjarmm.CustomFields{"warrantyExpiry": expiry.Unix()},
+//		RateLimit:    200 * time.Millisecond,
+//		DryRun:       true,
+//	})
+//	if err != nil {
+//		panic(err)
+//	}
+//	report.WriteCSV(os.Stdout)
+func BulkSetDeviceCustomFields(ctx context.Context, options BulkCustomFieldsOptions) (report BulkDeviceReport, err error) {
+	if (options.Patch == nil) == (options.PatchFunc == nil) {
+		err = errors.New("either a patch or a patch function is required")
+		return
+	}
+
+	devices, err := listAllDevices(ctx, options.DeviceFilter)
+	if err != nil {
+		err = fmt.Errorf("error listing devices: %w", err)
+		return
+	}
+
+	bulk := bulkOptions{
+		concurrency: options.Concurrency,
+		interval:    options.RateLimit,
+	}
+
+	report = bulkDevices(ctx, devices, bulk, func(ctx context.Context, device Device) (result BulkDeviceResult) {
+		patch := options.Patch
+		if options.PatchFunc != nil {
+			patch, result.Err = options.PatchFunc(device)
+			if result.Err != nil {
+				return
+			}
+		}
+
+		if len(patch) == 0 {
+			result.Status = BulkStatusSkipped
+			return
+		}
+
+		result.Changes = patch
+
+		if options.DryRun {
+			result.Status = BulkStatusDryRun
+			return
+		}
+
+		result.Err = setNodeCustomFields(ctx, DeviceNode(device.ID), patch)
+		return
+	})
+
+	return
+}
+
+// Options for BulkSetDeviceCustomFields
+type BulkCustomFieldsOptions struct {
+	// Device filter (See https://eu.ninjarmm.com/apidocs-beta/core-resources/articles/devices/device-filters)
+	DeviceFilter string
+
+	// Custom fields set on every device
+	Patch CustomFields
+
+	// Custom fields to set for a device, returning nil skips the device
+	PatchFunc func(device Device) (CustomFields, error)
+
+	// Number of devices updated in parallel (default: 4)
+	Concurrency int
+
+	// Minimum delay between two updates, 0 for no limit
+	RateLimit time.Duration
+
+	// Only report the changes, nothing is sent
+	DryRun bool
+}
+
+// Internal options of bulkDevices
+type bulkOptions struct {
+	concurrency int
+	interval    time.Duration
+}
+
+// Run `fn` for each device with bounded concurrency and rate limiting and
+// collect the results sorted by device ID
+func bulkDevices(ctx context.Context, devices []Device, options bulkOptions, fn func(ctx context.Context, device Device) BulkDeviceResult) (report BulkDeviceReport) {
+	if options.concurrency <= 0 {
+		options.concurrency = defaultBulkConcurrency
+	}
+
+	var limiter <-chan time.Time
+	if options.interval > 0 {
+		ticker := time.NewTicker(options.interval)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	report.Results = make([]BulkDeviceResult, len(devices))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, options.concurrency)
+
+	for i, device := range devices {
+		report.Results[i] = BulkDeviceResult{
+			DeviceID:   device.ID,
+			DeviceName: device.DisplayName,
+		}
+
+		// Wait for a free worker and the rate limiter, unless canceled
+		select {
+		case <-ctx.Done():
+			report.Results[i].Status = BulkStatusFailed
+			report.Results[i].Err = ctx.Err()
+			continue
+		case semaphore <- struct{}{}:
+		}
+
+		if limiter != nil && i > 0 {
+			select {
+			case <-ctx.Done():
+				<-semaphore
+				report.Results[i].Status = BulkStatusFailed
+				report.Results[i].Err = ctx.Err()
+				continue
+			case <-limiter:
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, device Device) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result := fn(ctx, device)
+			result.DeviceID = device.ID
+			result.DeviceName = device.DisplayName
+			if result.Err != nil {
+				result.Status = BulkStatusFailed
+			} else if result.Status == "" {
+				result.Status = BulkStatusUpdated
+			}
+			report.Results[i] = result
+		}(i, device)
+	}
+
+	wg.Wait()
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].DeviceID < report.Results[j].DeviceID
+	})
+
+	return
+}
+
+type BulkStatus string
+
+const (
+	BulkStatusUpdated BulkStatus = "UPDATED"
+	BulkStatusSkipped BulkStatus = "SKIPPED"
+	BulkStatusDryRun  BulkStatus = "DRY_RUN"
+	BulkStatusFailed  BulkStatus = "FAILED"
+)
+
+// Per-device outcome of a bulk operation
+type BulkDeviceResult struct {
+	DeviceID   int
+	DeviceName string
+	Status     BulkStatus
+	Changes    CustomFields // Custom fields sent (or that would be sent in dry-run)
+	Err        error
+}
+
+// Report of a bulk operation on devices
+type BulkDeviceReport struct {
+	Results []BulkDeviceResult
+}
+
+// Succeeded returns the IDs of the devices successfully processed.
+func (report BulkDeviceReport) Succeeded() (deviceIDs []int) {
+	for _, result := range report.Results {
+		if result.Err == nil {
+			deviceIDs = append(deviceIDs, result.DeviceID)
+		}
+	}
+	return
+}
+
+// Failed returns the results of the devices in error.
+func (report BulkDeviceReport) Failed() (results []BulkDeviceResult) {
+	for _, result := range report.Results {
+		if result.Err != nil {
+			results = append(results, result)
+		}
+	}
+	return
+}
+
+// HasErrors returns true if at least one device failed.
+func (report BulkDeviceReport) HasErrors() bool {
+	return len(report.Failed()) > 0
+}
+
+// WriteCSV writes the report as CSV with a header line.
+func (report BulkDeviceReport) WriteCSV(w io.Writer) (err error) {
+	writer := csv.NewWriter(w)
+
+	err = writer.Write([]string{"device_id", "device_name", "status", "changes", "error"})
+	if err != nil {
+		return
+	}
+
+	for _, result := range report.Results {
+		var changes, errorMessage string
+
+		if len(result.Changes) > 0 {
+			encoded, e := json.Marshal(result.Changes)
+			if e != nil {
+				return fmt.Errorf("error encoding changes of device %d: %w", result.DeviceID, e)
+			}
+			changes = string(encoded)
+		}
+
+		if result.Err != nil {
+			errorMessage = result.Err.Error()
+		}
+
+		err = writer.Write([]string{fmt.Sprint(result.DeviceID), result.DeviceName, string(result.Status), changes, errorMessage})
+		if err != nil {
+			return
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,306 @@
+package ninjarmm
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Response cache, see EnableCache
+	cache   *cacheConfig
+	cacheMu sync.RWMutex
+
+	organizationLocationsPath = regexp.MustCompile(`^organization/\d+/locations$`)
+)
+
+// Enable caching of reference data responses (roles, policies,
+// organizations and locations lists).
+//
+// Cached entries are invalidated after the TTL, explicitly with
+// InvalidateCache and ClearCache, or when the package itself mutates the
+// resource (e.g. CreateLocation invalidates the locations of the
+// organization).
+//
+// Usage:
+//
+//	ninjarmm.EnableCache(ninjarmm.CacheOptions{
+//		TTL:        10 * time.Minute,
+//		Operations: []ninjarmm.CacheOperation{ninjarmm.CacheListDeviceRoles, ninjarmm.CacheListDevicePolicies},
+//	})
+func EnableCache(options CacheOptions) {
+	if options.Cache == nil {
+		options.Cache = NewMemoryCache(1000)
+	}
+
+	if options.TTL <= 0 {
+		options.TTL = 5 * time.Minute
+	}
+
+	if len(options.Operations) == 0 {
+		options.Operations = []CacheOperation{
+			CacheListDeviceRoles,
+			CacheListDevicePolicies,
+			CacheListOrganizations,
+			CacheListOrganizationsDetailed,
+			CacheListOrganizationLocations,
+		}
+	}
+
+	config := &cacheConfig{
+		CacheOptions: options,
+		enabled:      make(map[CacheOperation]bool),
+	}
+
+	for _, operation := range options.Operations {
+		config.enabled[operation] = true
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = config
+}
+
+// Disable the response cache.
+func DisableCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = nil
+}
+
+// Invalidate the cached response of an operation. ListOrganizationLocations
+// requires the organization ID.
+func InvalidateCache(operation CacheOperation, ids ...int) {
+	config := currentCache()
+	if config == nil {
+		return
+	}
+
+	key := string(operation)
+	if strings.Contains(key, "%d") {
+		if len(ids) == 0 {
+			return
+		}
+		key = fmt.Sprintf(key, ids[0])
+	}
+
+	config.Cache.Delete(key)
+}
+
+// Remove all cached responses.
+func ClearCache() {
+	if config := currentCache(); config != nil {
+		config.Cache.Clear()
+	}
+}
+
+func currentCache() *cacheConfig {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cache
+}
+
+// Returns the cache operation of a GET path, if any
+func cacheOperationFor(path string) (operation CacheOperation, ok bool) {
+	switch {
+	case path == string(CacheListDeviceRoles):
+		return CacheListDeviceRoles, true
+	case path == string(CacheListDevicePolicies):
+		return CacheListDevicePolicies, true
+	case path == string(CacheListOrganizations):
+		return CacheListOrganizations, true
+	case path == string(CacheListOrganizationsDetailed):
+		return CacheListOrganizationsDetailed, true
+	case organizationLocationsPath.MatchString(path):
+		return CacheListOrganizationLocations, true
+	}
+	return
+}
+
+// Decode the cached response of a GET request, returns false on cache miss
+func lookupCache(method, path string, response interface{}) bool {
+	config := currentCache()
+	if config == nil || method != http.MethodGet || response == nil {
+		return false
+	}
+
+	if operation, ok := cacheOperationFor(path); !ok || !config.enabled[operation] {
+		return false
+	}
+
+	data, ok := config.Cache.Get(path)
+	if !ok {
+		return false
+	}
+
+	return json.Unmarshal(data, response) == nil
+}
+
+// Store the response of a GET request if its operation is cached
+func storeCache(path string, data []byte) {
+	config := currentCache()
+	if config == nil {
+		return
+	}
+
+	if operation, ok := cacheOperationFor(path); ok && config.enabled[operation] {
+		config.Cache.Set(path, data, config.TTL)
+	}
+}
+
+// Invalidate the cached responses affected by a mutation on `path`
+func invalidateCacheFor(path string) {
+	config := currentCache()
+	if config == nil {
+		return
+	}
+
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+
+	switch segments[0] {
+	case "organization", "organizations":
+		config.Cache.Delete(string(CacheListOrganizations))
+		config.Cache.Delete(string(CacheListOrganizationsDetailed))
+		if len(segments) > 2 && strings.HasPrefix(segments[2], "location") {
+			config.Cache.Delete(fmt.Sprintf("organization/%s/locations", segments[1]))
+		}
+	case "roles", "role":
+		config.Cache.Delete(string(CacheListDeviceRoles))
+	case "policies", "policy":
+		config.Cache.Delete(string(CacheListDevicePolicies))
+	}
+}
+
+// Storage of cached responses
+type Cache interface {
+	Get(key string) (value []byte, ok bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+	Clear()
+}
+
+// Options for EnableCache
+type CacheOptions struct {
+	// Storage of the responses (default: in-memory LRU of 1000 entries)
+	Cache Cache
+
+	// Lifetime of cached responses (default: 5 minutes)
+	TTL time.Duration
+
+	// Cached operations (default: all)
+	Operations []CacheOperation
+}
+
+type cacheConfig struct {
+	CacheOptions
+	enabled map[CacheOperation]bool
+}
+
+// Cacheable operation, the value is the path (template) of the request
+type CacheOperation string
+
+const (
+	CacheListDeviceRoles           CacheOperation = "roles"
+	CacheListDevicePolicies        CacheOperation = "policies"
+	CacheListOrganizations         CacheOperation = "organizations"
+	CacheListOrganizationsDetailed CacheOperation = "organizations-detailed"
+	CacheListOrganizationLocations CacheOperation = "organization/%d/locations"
+)
+
+// In-memory LRU cache with TTL, safe for concurrent use
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Most recently used first
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns an in-memory LRU cache holding at most `capacity`
+// entries (unlimited if <= 0).
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value of a non expired entry.
+func (c *MemoryCache) Get(key string) (value []byte, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[key]
+	if !found {
+		return
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores a value for `ttl`, evicting the least recently used entry when
+// the cache is full.
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[key]; found {
+		entry := element.Value.(*memoryCacheEntry)
+		entry.value = value
+		entry.expiresAt = time.Now().Add(ttl)
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	})
+
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete removes an entry.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[key]; found {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// Clear removes all entries.
+func (c *MemoryCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
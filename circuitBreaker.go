@@ -0,0 +1,362 @@
+package ninjarmm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Returned (wrapped in a *CircuitOpenError) when the circuit breaker of an
+// endpoint family is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+var (
+	// Circuit breaker, see EnableCircuitBreaker
+	breaker   *circuitBreaker
+	breakerMu sync.RWMutex
+)
+
+// Enable a circuit breaker in front of the API: once an endpoint family
+// (e.g. 'device', 'organization', 'query') fails too much, requests to it
+// fail fast with a *CircuitOpenError until a probe request succeeds.
+//
+// Network errors, 5xx and 429 responses are failures. Other 4xx responses
+// are considered successes since the API answered.
+//
+// Usage:
+//
+//	ninjarmm.EnableCircuitBreaker(ninjarmm.CircuitBreakerOptions{
+//		FailureThreshold: 5,
+//		OpenTimeout:      time.Minute,
+//		OnStateChange: func(family string, from, to ninjarmm.CircuitState) {
+//			log.Printf("circuit %s: %s -> %s", family, from, to)
+//		},
+//	})
+func EnableCircuitBreaker(options CircuitBreakerOptions) {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 5
+	}
+
+	if options.MinRequests <= 0 {
+		options.MinRequests = 20
+	}
+
+	if options.Window <= 0 {
+		options.Window = time.Minute
+	}
+
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = 30 * time.Second
+	}
+
+	if options.HalfOpenRequests <= 0 {
+		options.HalfOpenRequests = 1
+	}
+
+	if options.Family == nil {
+		options.Family = endpointFamily
+	}
+
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	breaker = &circuitBreaker{
+		options:  options,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// Disable the circuit breaker.
+func DisableCircuitBreaker() {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	breaker = nil
+}
+
+// Returns the state of the circuit of an endpoint family, closed when the
+// circuit breaker is disabled
+func CircuitBreakerState(family string) CircuitState {
+	breakerMu.RLock()
+	cb := breaker
+	breakerMu.RUnlock()
+
+	if cb == nil {
+		return CircuitClosed
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if c, ok := cb.circuits[family]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// Singular of the plural first path segments
+var endpointFamilies = map[string]string{
+	"activities":    "activity",
+	"alerts":        "alert",
+	"contacts":      "contact",
+	"devices":       "device",
+	"groups":        "group",
+	"locations":     "location",
+	"organizations": "organization",
+	"policies":      "policy",
+	"queries":       "query",
+	"roles":         "role",
+	"users":         "user",
+	"webhooks":      "webhook",
+}
+
+// Default endpoint family: first path segment in the singular without
+// suffixes, e.g. 'device' for "devices-detailed" and "device/1/custom-fields"
+func endpointFamily(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	family, _, _ := strings.Cut(path, "/")
+	family, _, _ = strings.Cut(family, "-")
+	if singular, ok := endpointFamilies[family]; ok {
+		return singular
+	}
+	return family
+}
+
+// Check if a request is allowed, `done` must be called with the request
+// result when it is
+func allowCircuit(path string) (done func(err error), err error) {
+	breakerMu.RLock()
+	cb := breaker
+	breakerMu.RUnlock()
+
+	if cb == nil {
+		return func(error) {}, nil
+	}
+
+	return cb.allow(cb.options.Family(path))
+}
+
+func (cb *circuitBreaker) allow(family string) (done func(err error), err error) {
+	defer cb.notify()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[family]
+	if !ok {
+		c = &circuit{state: CircuitClosed, windowStart: time.Now()}
+		cb.circuits[family] = c
+	}
+
+	now := time.Now()
+
+	switch c.state {
+	case CircuitOpen:
+		if now.Before(c.openedAt.Add(cb.options.OpenTimeout)) {
+			err = &CircuitOpenError{Family: family, RetryAfter: c.openedAt.Add(cb.options.OpenTimeout).Sub(now)}
+			return
+		}
+		cb.setState(family, c, CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if c.probes >= cb.options.HalfOpenRequests {
+			err = &CircuitOpenError{Family: family}
+			return
+		}
+		c.probes++
+	}
+
+	// Results are only counted in the state the request was admitted in
+	probe, generation := c.state == CircuitHalfOpen, c.generation
+	return func(err error) { cb.record(family, c, probe, generation, err) }, nil
+}
+
+// Record the result of a request admitted as a probe or not, in the
+// `generation` of the circuit
+func (cb *circuitBreaker) record(family string, c *circuit, probe bool, generation int, err error) {
+	defer cb.notify()
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if c.generation != generation {
+		// The circuit changed state since the request was admitted, e.g. a
+		// request sent before the circuit opened completing after it
+		return
+	}
+
+	failed := isCircuitFailure(err)
+	now := time.Now()
+
+	if probe {
+		c.probes--
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// The caller gave up, no proof the API recovered nor failed
+			return
+		}
+
+		if failed {
+			c.openedAt = now
+			cb.setState(family, c, CircuitOpen)
+		} else {
+			c.reset(now)
+			cb.setState(family, c, CircuitClosed)
+		}
+		return
+	}
+
+	if now.Sub(c.windowStart) > cb.options.Window {
+		c.windowStart, c.requests, c.failures = now, 0, 0
+	}
+
+	c.requests++
+	if failed {
+		c.failures++
+		c.consecutiveFailures++
+	} else {
+		c.consecutiveFailures = 0
+	}
+
+	tooManyConsecutive := c.consecutiveFailures >= cb.options.FailureThreshold
+	tooHighRate := cb.options.ErrorRateThreshold > 0 && c.requests >= cb.options.MinRequests &&
+		float64(c.failures)/float64(c.requests) >= cb.options.ErrorRateThreshold
+
+	if tooManyConsecutive || tooHighRate {
+		c.openedAt = now
+		cb.setState(family, c, CircuitOpen)
+	}
+}
+
+func (cb *circuitBreaker) setState(family string, c *circuit, state CircuitState) {
+	if c.state == state {
+		return
+	}
+
+	from := c.state
+	c.state = state
+	c.generation++
+
+	if cb.options.OnStateChange != nil {
+		cb.changes = append(cb.changes, circuitChange{family, from, state})
+	}
+}
+
+// Deliver the queued state changes to OnStateChange in order, without
+// holding the lock. Changes queued while another goroutine delivers (or by
+// OnStateChange itself) are delivered by that goroutine.
+func (cb *circuitBreaker) notify() {
+	cb.mu.Lock()
+	if cb.delivering {
+		cb.mu.Unlock()
+		return
+	}
+	cb.delivering = true
+
+	for len(cb.changes) > 0 {
+		change := cb.changes[0]
+		cb.changes = cb.changes[1:]
+		cb.mu.Unlock()
+
+		cb.options.OnStateChange(change.family, change.from, change.to)
+
+		cb.mu.Lock()
+	}
+
+	cb.delivering = false
+	cb.mu.Unlock()
+}
+
+// Check if a request error must be counted as a failure
+func isCircuitFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode >= 500 || apiError.StatusCode == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+func (c *circuit) reset(now time.Time) {
+	c.windowStart, c.requests, c.failures, c.consecutiveFailures, c.probes = now, 0, 0, 0, 0
+}
+
+// Options for EnableCircuitBreaker
+type CircuitBreakerOptions struct {
+	// Consecutive failures opening the circuit (default: 5)
+	FailureThreshold int
+
+	// Failure rate (0 to 1) opening the circuit, 0 disables it
+	ErrorRateThreshold float64
+
+	// Minimum requests in the window before the failure rate is checked (default: 20)
+	MinRequests int
+
+	// Duration of the failure rate window (default: 1 minute)
+	Window time.Duration
+
+	// Delay before an open circuit lets probe requests through (default: 30 seconds)
+	OpenTimeout time.Duration
+
+	// Number of concurrent probe requests when half-open (default: 1)
+	HalfOpenRequests int
+
+	// Called when the circuit of a family changes state, after the change
+	// and in order, by the goroutine of the request that triggered it
+	OnStateChange func(family string, from, to CircuitState)
+
+	// Returns the endpoint family of a request path, defaults to the first
+	// path segment (e.g. 'device', 'organization', 'ticketing')
+	Family func(path string) string
+}
+
+type circuitBreaker struct {
+	options    CircuitBreakerOptions
+	mu         sync.Mutex
+	circuits   map[string]*circuit
+	changes    []circuitChange // State changes to deliver to OnStateChange
+	delivering bool
+}
+
+type circuitChange struct {
+	family   string
+	from, to CircuitState
+}
+
+// State of the circuit of an endpoint family
+type circuit struct {
+	state               CircuitState
+	openedAt            time.Time
+	windowStart         time.Time
+	requests            int
+	failures            int
+	consecutiveFailures int
+	probes              int
+	generation          int // Incremented on every state change
+}
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "CLOSED"
+	CircuitOpen     CircuitState = "OPEN"
+	CircuitHalfOpen CircuitState = "HALF_OPEN"
+)
+
+// Error returned when the circuit of an endpoint family is open
+type CircuitOpenError struct {
+	Family     string
+	RetryAfter time.Duration // Time before the next probe, 0 if unknown
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for '%s' endpoints", e.Family)
+}
+
+// Is makes errors.Is(err, ErrCircuitOpen) true.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
@@ -0,0 +1,435 @@
+package ninjarmm
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Directory attributes (lower case, without punctuation) → entry field
+var directoryAttributes = map[string]string{
+	"email":           "email",
+	"mail":            "email",
+	"emailaddress":    "email",
+	"firstname":       "firstName",
+	"givenname":       "firstName",
+	"lastname":        "lastName",
+	"sn":              "lastName",
+	"surname":         "lastName",
+	"phone":           "phone",
+	"telephonenumber": "phone",
+	"jobtitle":        "jobTitle",
+	"title":           "jobTitle",
+}
+
+// Read a directory export as CSV with a header line. Recognized columns are
+// email (or mail), first name (or givenName), last name (or sn), phone (or
+// telephoneNumber) and job title (or title), other columns are ignored.
+func ReadDirectoryCSV(r io.Reader) (entries []DirectoryEntry, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		err = fmt.Errorf("error reading directory header: %w", err)
+		return
+	}
+
+	columns := make(map[int]string, len(header))
+	for i, name := range header {
+		if field, ok := directoryAttributes[normalizeAttribute(name)]; ok {
+			columns[i] = field
+		}
+	}
+
+	hasEmail := false
+	for _, field := range columns {
+		hasEmail = hasEmail || field == "email"
+	}
+	if !hasEmail {
+		err = errors.New("directory has no email column")
+		return
+	}
+
+	for line := 2; ; line++ {
+		var record []string
+		record, err = reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			err = fmt.Errorf("error reading directory: %w", err)
+			return
+		}
+
+		var entry DirectoryEntry
+		for i, value := range record {
+			if field, ok := columns[i]; ok {
+				entry.set(field, strings.TrimSpace(value))
+			}
+		}
+
+		if entry.Email == "" {
+			err = fmt.Errorf("directory line %d has no email", line)
+			return
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+// Read a directory export as LDIF (e.g. from ldapsearch). Entries without
+// mail attribute (groups, organizational units...) are skipped.
+func ReadDirectoryLDIF(r io.Reader) (entries []DirectoryEntry, err error) {
+	scanner := bufio.NewScanner(r)
+
+	var entry DirectoryEntry
+	var lines []string
+
+	flushLine := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		line := strings.Join(lines, "")
+		lines = nil
+
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return fmt.Errorf("invalid LDIF line '%s'", line)
+		}
+
+		switch {
+		case strings.HasPrefix(value, ":"):
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+			if err != nil {
+				return fmt.Errorf("invalid base64 value of '%s': %w", name, err)
+			}
+			value = string(decoded)
+		case strings.HasPrefix(value, "<"):
+			// Values from URLs are not supported
+			return nil
+		}
+
+		if field, ok := directoryAttributes[normalizeAttribute(name)]; ok {
+			entry.set(field, strings.TrimSpace(value))
+		}
+		return nil
+	}
+
+	flushEntry := func() {
+		if entry.Email != "" {
+			entries = append(entries, entry)
+		}
+		entry = DirectoryEntry{}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, " "):
+			// Folded line
+			lines = append(lines, line[1:])
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+
+		if err = flushLine(); err != nil {
+			return
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushEntry()
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err = scanner.Err(); err != nil {
+		err = fmt.Errorf("error reading directory: %w", err)
+		return
+	}
+
+	if err = flushLine(); err != nil {
+		return
+	}
+	flushEntry()
+
+	return
+}
+
+// Lower case and remove punctuation: 'First Name' and 'first_name' become
+// 'firstname'
+func normalizeAttribute(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func (entry *DirectoryEntry) set(field, value string) {
+	switch field {
+	case "email":
+		entry.Email = value
+	case "firstName":
+		entry.FirstName = value
+	case "lastName":
+		entry.LastName = value
+	case "phone":
+		entry.Phone = value
+	case "jobTitle":
+		entry.JobTitle = value
+	}
+}
+
+// Compute the changes needed to make the contacts of an organization
+// (ClientID) match a directory export.
+//
+// Contacts are matched by email (case insensitive). Empty directory values
+// are not managed. Contacts can't be disabled in NinjaOne, with
+// `options.DeactivateMissing` the contacts missing from the directory are
+// deactivated by tagging their job title (see ContactSyncOptions), they are
+// never deleted. Deactivated contacts back in the directory lose the tag.
+func PlanContactSync(clientID int, entries []DirectoryEntry, options ContactSyncOptions) (plan ContactSyncPlan, err error) {
+	contacts, err := ListContacts()
+	if err != nil {
+		err = fmt.Errorf("error listing contacts: %w", err)
+		return
+	}
+
+	var organizationContacts []Contact
+	for _, contact := range contacts {
+		if contact.ClientID == clientID {
+			organizationContacts = append(organizationContacts, contact)
+		}
+	}
+
+	return buildContactSyncPlan(clientID, organizationContacts, entries, options)
+}
+
+// Compare the contacts of an organization against directory entries
+func buildContactSyncPlan(clientID int, contacts []Contact, entries []DirectoryEntry, options ContactSyncOptions) (plan ContactSyncPlan, err error) {
+	plan.ClientID = clientID
+
+	byEmail := make(map[string]Contact, len(contacts))
+	for _, contact := range contacts {
+		byEmail[strings.ToLower(contact.Email)] = contact
+	}
+
+	seen := make(map[string]bool, len(entries))
+
+	for _, entry := range entries {
+		email := strings.ToLower(entry.Email)
+		if seen[email] {
+			err = fmt.Errorf("email '%s' appears twice in the directory", entry.Email)
+			return
+		}
+		seen[email] = true
+
+		current, exists := byEmail[email]
+		change := ContactChange{Action: ContactActionCreate, Contact: current}
+
+		if !exists {
+			change.Contact = Contact{ClientID: clientID, Email: entry.Email}
+		}
+
+		desired := change.Contact
+		if title, deactivated := options.untag(desired.JobTitle); deactivated && entry.JobTitle == "" {
+			change.Diffs = append(change.Diffs, PlanDiff{Field: "jobTitle", Old: desired.JobTitle, New: title})
+			desired.JobTitle = title
+		}
+
+		for _, field := range []struct {
+			name    string
+			current *string
+			value   string
+		}{
+			{"firstName", &desired.FirstName, entry.FirstName},
+			{"lastName", &desired.LastName, entry.LastName},
+			{"phone", &desired.Phone, entry.Phone},
+			{"jobTitle", &desired.JobTitle, entry.JobTitle},
+		} {
+			if field.value != "" && field.value != *field.current {
+				change.Diffs = append(change.Diffs, PlanDiff{Field: field.name, Old: *field.current, New: field.value})
+				*field.current = field.value
+			}
+		}
+		change.Contact = desired
+
+		if exists {
+			change.Action = ContactActionNoOp
+			if len(change.Diffs) > 0 {
+				change.Action = ContactActionUpdate
+			}
+		}
+
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, contact := range contacts {
+		if seen[strings.ToLower(contact.Email)] {
+			continue
+		}
+
+		change := ContactChange{Action: ContactActionNoOp, Contact: contact}
+		if _, deactivated := options.untag(contact.JobTitle); options.DeactivateMissing && !deactivated {
+			change.Action = ContactActionDeactivate
+			change.Contact.JobTitle = options.tag(contact.JobTitle)
+			change.Diffs = []PlanDiff{{Field: "jobTitle", Old: contact.JobTitle, New: change.Contact.JobTitle}}
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return strings.ToLower(plan.Changes[i].Contact.Email) < strings.ToLower(plan.Changes[j].Contact.Email)
+	})
+
+	return
+}
+
+// HasChanges returns true if applying the plan changes something.
+func (plan ContactSyncPlan) HasChanges() bool {
+	for _, change := range plan.Changes {
+		if change.Action != ContactActionNoOp {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the plan, one line per created (+), updated (~) or
+// deactivated (-) contact followed by its field changes and a summary.
+func (plan ContactSyncPlan) String() string {
+	var builder strings.Builder
+	counts := make(map[ContactAction]int)
+
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+		if change.Action == ContactActionNoOp {
+			continue
+		}
+
+		builder.WriteString(change.String())
+		builder.WriteString("\n")
+
+		for _, diff := range change.Diffs {
+			fmt.Fprintf(&builder, "    %s: %s -> %s\n", diff.Field, formatPlanValue(diff.Old), formatPlanValue(diff.New))
+		}
+	}
+
+	fmt.Fprintf(&builder, "Plan: %d to create, %d to update, %d to deactivate, %d unchanged.", counts[ContactActionCreate], counts[ContactActionUpdate], counts[ContactActionDeactivate], counts[ContactActionNoOp])
+	return builder.String()
+}
+
+// String describes the change on one line.
+func (change ContactChange) String() string {
+	symbol := map[ContactAction]string{
+		ContactActionCreate:     "+",
+		ContactActionUpdate:     "~",
+		ContactActionDeactivate: "-",
+		ContactActionNoOp:       "=",
+	}[change.Action]
+
+	contact := fmt.Sprintf("contact '%s'", change.Contact.Email)
+	if change.Contact.ID != 0 {
+		contact += fmt.Sprintf(" (%d)", change.Contact.ID)
+	}
+
+	return fmt.Sprintf("%s %s %s", symbol, strings.ToLower(strings.ReplaceAll(string(change.Action), "_", "-")), contact)
+}
+
+// Apply the plan with CreateContact and UpdateContact.
+//
+// Apply stops at the first error, computing a new plan shows what remains to
+// be done.
+func (plan ContactSyncPlan) Apply() (err error) {
+	for _, change := range plan.Changes {
+		switch change.Action {
+		case ContactActionCreate:
+			_, err = CreateContact(change.Contact)
+		case ContactActionUpdate, ContactActionDeactivate:
+			err = UpdateContact(change.Contact)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("error applying '%s': %w", strings.TrimSpace(change.String()[1:]), err)
+			return
+		}
+	}
+
+	return
+}
+
+// Person of a directory export, see ReadDirectoryCSV and ReadDirectoryLDIF
+type DirectoryEntry struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Phone     string
+	JobTitle  string
+}
+
+// Tag of deactivated contacts, see ContactSyncOptions
+const DefaultContactDeactivatedTag = "[Inactive]"
+
+// Options for PlanContactSync
+type ContactSyncOptions struct {
+	// Deactivate the contacts of the organization missing from the
+	// directory: their job title is prefixed with DeactivatedTag
+	DeactivateMissing bool
+
+	// Job title prefix of deactivated contacts (default: "[Inactive]")
+	DeactivatedTag string
+}
+
+func (options ContactSyncOptions) deactivatedTag() string {
+	if options.DeactivatedTag == "" {
+		return DefaultContactDeactivatedTag
+	}
+	return options.DeactivatedTag
+}
+
+// Job title of a deactivated contact
+func (options ContactSyncOptions) tag(jobTitle string) string {
+	if jobTitle == "" {
+		return options.deactivatedTag()
+	}
+	return options.deactivatedTag() + " " + jobTitle
+}
+
+// Job title without the deactivated tag, `deactivated` is true if it had it
+func (options ContactSyncOptions) untag(jobTitle string) (title string, deactivated bool) {
+	title, deactivated = strings.CutPrefix(jobTitle, options.deactivatedTag())
+	return strings.TrimSpace(title), deactivated
+}
+
+// Changes needed to make the contacts of an organization match a
+// directory, see PlanContactSync
+type ContactSyncPlan struct {
+	ClientID int
+	Changes  []ContactChange // Sorted by email
+}
+
+type ContactChange struct {
+	Action  ContactAction
+	Contact Contact // Contact as it will be after the change
+	Diffs   []PlanDiff
+}
+
+type ContactAction string
+
+const (
+	ContactActionCreate     ContactAction = "CREATE"
+	ContactActionUpdate     ContactAction = "UPDATE"
+	ContactActionDeactivate ContactAction = "DEACTIVATE"
+	ContactActionNoOp       ContactAction = "NO_OP"
+)
@@ -0,0 +1,104 @@
+package ninjarmm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Returns a ticketing contact
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getContact
+func GetContact(contactID int) (contact Contact, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("ticketing/contact/contacts/%d", contactID), nil, &contact)
+	return
+}
+
+// Create a ticketing contact for an organization (ClientID)
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/createContact
+func CreateContact(contact Contact) (createdContact Contact, err error) {
+	if contact.ClientID == 0 {
+		err = errors.New("client ID required")
+		return
+	}
+
+	err = request(http.MethodPost, "ticketing/contact/contacts", contact.payload(), &createdContact)
+	return
+}
+
+// Update a ticketing contact
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateContact
+func UpdateContact(contact Contact) (err error) {
+	if contact.ID == 0 {
+		err = errors.New("contact ID required")
+		return
+	}
+
+	err = request(http.MethodPut, fmt.Sprintf("ticketing/contact/contacts/%d", contact.ID), contact.payload(), nil)
+	return
+}
+
+// Update a ticketing contact
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateContact
+func (contact Contact) Update() (err error) {
+	return UpdateContact(contact)
+}
+
+// Delete a ticketing contact
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/deleteContact
+func DeleteContact(contactID int) (err error) {
+	err = request(http.MethodDelete, fmt.Sprintf("ticketing/contact/contacts/%d", contactID), nil, nil)
+	return
+}
+
+// Find a ticketing contact by email (case insensitive)
+func FindContactByEmail(email string) (contact Contact, err error) {
+	return findContact("email", email, func(c Contact) bool { return strings.EqualFold(c.Email, email) })
+}
+
+// Find a ticketing contact by UID
+func FindContactByUID(uid string) (contact Contact, err error) {
+	return findContact("UID", uid, func(c Contact) bool { return c.UID == uid })
+}
+
+func findContact(kind, value string, matches func(Contact) bool) (contact Contact, err error) {
+	contacts, err := ListContacts()
+	if err != nil {
+		return
+	}
+
+	for _, c := range contacts {
+		if matches(c) {
+			return c, nil
+		}
+	}
+
+	err = fmt.Errorf("no contact with %s '%s'", kind, value)
+	return
+}
+
+// Writable fields of a contact
+func (contact Contact) payload() contactPayload {
+	return contactPayload{
+		ClientID:  contact.ClientID,
+		FirstName: contact.FirstName,
+		LastName:  contact.LastName,
+		Email:     contact.Email,
+		Phone:     contact.Phone,
+		JobTitle:  contact.JobTitle,
+	}
+}
+
+type contactPayload struct {
+	ClientID  int    `json:"clientId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	JobTitle  string `json:"jobTitle"` // Sent empty to clear it, updates replace the contact
+}
@@ -0,0 +1,424 @@
+package ninjarmm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Definitions used to validate custom fields before sending them,
+	// see UseCustomFieldDefinitions
+	customFieldDefinitions   CustomFieldDefinitions
+	customFieldDefinitionsMu sync.RWMutex
+)
+
+// List custom field definitions, optionally filtered by scopes
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getNodeCustomFields
+func ListCustomFieldDefinitions(scopes ...CustomFieldScope) (definitions CustomFieldDefinitions, err error) {
+	values := url.Values{}
+
+	if len(scopes) > 0 {
+		list := make([]string, len(scopes))
+		for i, scope := range scopes {
+			list[i] = string(scope)
+		}
+		values.Set("scopes", strings.Join(list, ","))
+	} else {
+		values.Set("scopes", "ALL")
+	}
+
+	err = request(http.MethodGet, "device-custom-fields?"+values.Encode(), nil, &definitions)
+	return
+}
+
+// Use custom field definitions to validate and encode values in
+// SetDeviceCustomFields, SetOrganizationCustomFields and
+// SetLocationCustomFields. Use nil to disable validation.
+//
+// Usage:
+//
+//	definitions, err := ninjarmm.ListCustomFieldDefinitions()
+//	if err != nil {
+//		panic(err)
+//	}
+//	ninjarmm.UseCustomFieldDefinitions(definitions)
+func UseCustomFieldDefinitions(definitions CustomFieldDefinitions) {
+	customFieldDefinitionsMu.Lock()
+	defer customFieldDefinitionsMu.Unlock()
+	customFieldDefinitions = definitions
+}
+
+// Validate and encode custom fields with the definitions set by
+// UseCustomFieldDefinitions, if any
+func prepareCustomFields(customFields CustomFields) (CustomFields, error) {
+	customFieldDefinitionsMu.RLock()
+	definitions := customFieldDefinitions
+	customFieldDefinitionsMu.RUnlock()
+
+	if definitions == nil {
+		return customFields, nil
+	}
+
+	return definitions.Encode(customFields)
+}
+
+type CustomFieldDefinitions []CustomFieldDefinition
+
+// ByName returns the definition of the custom field `name`.
+func (definitions CustomFieldDefinitions) ByName(name string) (definition CustomFieldDefinition, ok bool) {
+	for _, definition := range definitions {
+		if definition.Name == name {
+			return definition, true
+		}
+	}
+	return
+}
+
+// Decode converts raw API values to typed values according to their
+// definition. Fields without definition are kept as is.
+func (definitions CustomFieldDefinitions) Decode(customFields CustomFields) (decoded CustomFields, err error) {
+	decoded = make(CustomFields, len(customFields))
+	var errs []error
+
+	for key, raw := range customFields {
+		definition, ok := definitions.ByName(key)
+		if !ok {
+			decoded[key] = raw
+			continue
+		}
+
+		value, e := definition.Decode(raw)
+		if e != nil {
+			errs = append(errs, e)
+			continue
+		}
+		decoded[key] = value
+	}
+
+	err = errors.Join(errs...)
+	return
+}
+
+// Encode validates typed values and converts them to the format expected by
+// the API. Fields without definition are kept as is.
+func (definitions CustomFieldDefinitions) Encode(customFields CustomFields) (encoded CustomFields, err error) {
+	encoded = make(CustomFields, len(customFields))
+	var errs []error
+
+	for key, value := range customFields {
+		definition, ok := definitions.ByName(key)
+		if !ok {
+			encoded[key] = value
+			continue
+		}
+
+		raw, e := definition.Encode(value)
+		if e != nil {
+			errs = append(errs, e)
+			continue
+		}
+		encoded[key] = raw
+	}
+
+	err = errors.Join(errs...)
+	return
+}
+
+// Validate checks the values against their definitions.
+func (definitions CustomFieldDefinitions) Validate(customFields CustomFields) (err error) {
+	_, err = definitions.Encode(customFields)
+	return
+}
+
+// Decode converts a raw API value to its typed value:
+//
+//   - DATE and DATE_TIME as time.Time
+//   - TIME as time.Duration since midnight
+//   - DROPDOWN as the option label
+//   - MULTI_SELECT as []string of option labels
+//   - WYSIWYG as the HTML string
+//   - CHECKBOX as bool, NUMERIC as int and DECIMAL as float64
+//   - everything else as string
+func (definition CustomFieldDefinition) Decode(raw interface{}) (value interface{}, err error) {
+	if raw == nil {
+		return
+	}
+
+	fields := CustomFields{"value": raw}
+
+	switch definition.Type {
+	case CustomFieldTypeDropdown:
+		option, ok := definition.option(fields.StringField("value"))
+		if !ok {
+			err = definition.errorf("unknown option '%v'", raw)
+			return
+		}
+		value = option.Name
+	case CustomFieldTypeMultiSelect:
+		labels := []string{}
+		for _, id := range fields.StringsField("value") {
+			option, ok := definition.option(id)
+			if !ok {
+				err = definition.errorf("unknown option '%s'", id)
+				return
+			}
+			labels = append(labels, option.Name)
+		}
+		value = labels
+	case CustomFieldTypeCheckbox:
+		value = fields.BoolField("value")
+	case CustomFieldTypeNumeric:
+		value = fields.IntField("value")
+	case CustomFieldTypeDecimal:
+		value = fields.FloatField("value")
+	case CustomFieldTypeDate, CustomFieldTypeDateTime:
+		value = fields.TimeField("value")
+	case CustomFieldTypeTime:
+		value = time.Duration(fields.IntField("value")) * time.Second
+	case CustomFieldTypeWYSIWYG:
+		if html, ok := raw.(map[string]interface{}); ok {
+			value = CustomFields(html).StringField("html")
+		} else {
+			value = fields.StringField("value")
+		}
+	default:
+		value = fields.StringField("value")
+	}
+
+	return
+}
+
+// Encode validates a typed value (see Decode for the types) and converts it
+// to the format expected by the API. Raw API values are accepted too.
+func (definition CustomFieldDefinition) Encode(value interface{}) (raw interface{}, err error) {
+	if value == nil {
+		if definition.Content.Required {
+			err = definition.errorf("value required")
+		}
+		return
+	}
+
+	switch definition.Type {
+	case CustomFieldTypeDropdown:
+		label, ok := value.(string)
+		if !ok {
+			err = definition.errorf("expected string, got %T", value)
+			return
+		}
+		option, ok := definition.option(label)
+		if !ok {
+			err = definition.errorf("unknown option '%s'", label)
+			return
+		}
+		raw = option.ID
+	case CustomFieldTypeMultiSelect:
+		var labels []string
+		switch v := value.(type) {
+		case []string:
+			labels = v
+		case string:
+			labels = CustomFields{"value": v}.StringsField("value")
+		default:
+			err = definition.errorf("expected []string, got %T", value)
+			return
+		}
+		ids := []string{}
+		for _, label := range labels {
+			option, ok := definition.option(label)
+			if !ok {
+				err = definition.errorf("unknown option '%s'", label)
+				return
+			}
+			ids = append(ids, option.ID)
+		}
+		raw = ids
+	case CustomFieldTypeCheckbox:
+		if _, ok := value.(bool); !ok {
+			err = definition.errorf("expected bool, got %T", value)
+			return
+		}
+		raw = value
+	case CustomFieldTypeNumeric:
+		switch v := value.(type) {
+		case int, int32, int64:
+			raw = v
+		case float64:
+			if v != float64(int64(v)) {
+				err = definition.errorf("expected integer, got %v", v)
+				return
+			}
+			raw = int64(v)
+		default:
+			err = definition.errorf("expected integer, got %T", value)
+		}
+	case CustomFieldTypeDecimal:
+		switch v := value.(type) {
+		case float32, float64, int, int32, int64:
+			raw = v
+		default:
+			err = definition.errorf("expected number, got %T", value)
+		}
+	case CustomFieldTypeDate, CustomFieldTypeDateTime:
+		switch v := value.(type) {
+		case time.Time:
+			raw = v.Unix()
+		case Time:
+			raw = time.Time(v).Unix()
+		case int, int64, float64:
+			raw = v
+		default:
+			err = definition.errorf("expected time.Time, got %T", value)
+		}
+	case CustomFieldTypeTime:
+		switch v := value.(type) {
+		case time.Duration:
+			raw = int64(v / time.Second)
+		case int, int64, float64:
+			raw = v
+		default:
+			err = definition.errorf("expected time.Duration, got %T", value)
+		}
+	case CustomFieldTypeWYSIWYG:
+		html, ok := value.(string)
+		if !ok {
+			err = definition.errorf("expected HTML string, got %T", value)
+			return
+		}
+		raw = map[string]interface{}{"html": html}
+	default:
+		text, ok := value.(string)
+		if !ok {
+			err = definition.errorf("expected string, got %T", value)
+			return
+		}
+		if err = definition.validateText(text); err != nil {
+			return
+		}
+		raw = text
+	}
+
+	return
+}
+
+// Validate checks a typed value against the definition.
+func (definition CustomFieldDefinition) Validate(value interface{}) (err error) {
+	_, err = definition.Encode(value)
+	return
+}
+
+// Check format of text based custom fields
+func (definition CustomFieldDefinition) validateText(text string) error {
+	if text == "" {
+		if definition.Content.Required {
+			return definition.errorf("value required")
+		}
+		return nil
+	}
+
+	switch definition.Type {
+	case CustomFieldTypeEmail:
+		if _, err := mail.ParseAddress(text); err != nil {
+			return definition.errorf("invalid email '%s'", text)
+		}
+	case CustomFieldTypeIPAddress:
+		if net.ParseIP(text) == nil {
+			return definition.errorf("invalid IP address '%s'", text)
+		}
+	case CustomFieldTypeURL:
+		if parsed, err := url.Parse(text); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return definition.errorf("invalid URL '%s'", text)
+		}
+	}
+
+	return nil
+}
+
+// Find a dropdown option by GUID or label (case insensitive)
+func (definition CustomFieldDefinition) option(idOrLabel string) (option CustomFieldOption, ok bool) {
+	for _, option := range definition.Content.Values {
+		if option.ID == idOrLabel {
+			return option, true
+		}
+	}
+	for _, option := range definition.Content.Values {
+		if strings.EqualFold(option.Name, idOrLabel) {
+			return option, true
+		}
+	}
+	return
+}
+
+func (definition CustomFieldDefinition) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid value for custom field '%s': %s", definition.Name, fmt.Sprintf(format, args...))
+}
+
+type CustomFieldDefinition struct {
+	ID                   int                `json:"id"`
+	Name                 string             `json:"name"`
+	Label                string             `json:"label"`
+	Description          string             `json:"description"`
+	Type                 CustomFieldType    `json:"type"`
+	DefinitionScope      []CustomFieldScope `json:"definitionScope"`
+	TechnicianPermission string             `json:"technicianPermission"`
+	ScriptPermission     string             `json:"scriptPermission"`
+	APIPermission        string             `json:"apiPermission"`
+	DefaultValue         any                `json:"defaultValue"`
+	Content              CustomFieldContent `json:"content"`
+	CreateTime           Time               `json:"createTime"`
+	UpdateTime           Time               `json:"updateTime"`
+}
+
+type CustomFieldContent struct {
+	Values           []CustomFieldOption `json:"values"` // Dropdown and multi-select options
+	Required         bool                `json:"required"`
+	FooterText       string              `json:"footerText"`
+	TooltipText      string              `json:"tooltipText"`
+	AdvancedSettings map[string]any      `json:"advancedSettings"`
+}
+
+type CustomFieldOption struct {
+	ID     string `json:"id"` // GUID
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+type CustomFieldType string
+
+const (
+	CustomFieldTypeAttachment    CustomFieldType = "ATTACHMENT"
+	CustomFieldTypeCheckbox      CustomFieldType = "CHECKBOX"
+	CustomFieldTypeDate          CustomFieldType = "DATE"
+	CustomFieldTypeDateTime      CustomFieldType = "DATE_TIME"
+	CustomFieldTypeDecimal       CustomFieldType = "DECIMAL"
+	CustomFieldTypeDropdown      CustomFieldType = "DROPDOWN"
+	CustomFieldTypeEmail         CustomFieldType = "EMAIL"
+	CustomFieldTypeIPAddress     CustomFieldType = "IP_ADDRESS"
+	CustomFieldTypeMultiline     CustomFieldType = "MULTILINE"
+	CustomFieldTypeMultiSelect   CustomFieldType = "MULTI_SELECT"
+	CustomFieldTypeNumeric       CustomFieldType = "NUMERIC"
+	CustomFieldTypePhone         CustomFieldType = "PHONE"
+	CustomFieldTypeSecure        CustomFieldType = "TEXT_ENCRYPTED"
+	CustomFieldTypeText          CustomFieldType = "TEXT"
+	CustomFieldTypeTextMultiline CustomFieldType = "TEXT_MULTILINE"
+	CustomFieldTypeTime          CustomFieldType = "TIME"
+	CustomFieldTypeURL           CustomFieldType = "URL"
+	CustomFieldTypeWYSIWYG       CustomFieldType = "WYSIWYG"
+)
+
+type CustomFieldScope string
+
+const (
+	CustomFieldScopeAll          CustomFieldScope = "ALL"
+	CustomFieldScopeNode         CustomFieldScope = "NODE"
+	CustomFieldScopeLocation     CustomFieldScope = "LOCATION"
+	CustomFieldScopeOrganization CustomFieldScope = "ORGANIZATION"
+	CustomFieldScopeEndUser      CustomFieldScope = "END_USER"
+)
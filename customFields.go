@@ -3,6 +3,8 @@ package ninjarmm
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Shortcuts for map[string]interface{}
@@ -96,3 +98,72 @@ func (c CustomFields) BoolField(key string) bool {
 		return false
 	}
 }
+
+// TimeField returns a parsed time.Time value from the custom field.
+//
+// Numbers are read as unix timestamps (seconds or milliseconds), strings as
+// RFC 3339 or 'YYYY-MM-DD' dates.
+func (c CustomFields) TimeField(key string) time.Time {
+	switch i := c[key].(type) {
+	case time.Time:
+		return i
+	case Time:
+		return time.Time(i)
+	case float64:
+		return unixTime(i)
+	case int:
+		return unixTime(float64(i))
+	case int64:
+		return unixTime(float64(i))
+	case string:
+		if conv, err := strconv.ParseFloat(i, 64); err == nil {
+			return unixTime(conv)
+		}
+		for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+			if parsed, err := time.Parse(layout, i); err == nil {
+				return parsed
+			}
+		}
+		return time.Time{}
+	default:
+		return time.Time{}
+	}
+}
+
+// StringsField returns a []string value from the custom field.
+//
+// Comma separated strings are split.
+func (c CustomFields) StringsField(key string) []string {
+	switch i := c[key].(type) {
+	case []string:
+		return i
+	case []interface{}:
+		values := make([]string, 0, len(i))
+		for _, v := range i {
+			values = append(values, CustomFields{"v": v}.StringField("v"))
+		}
+		return values
+	case string:
+		values := []string{}
+		for _, v := range strings.Split(i, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	case nil:
+		return nil
+	default:
+		return []string{fmt.Sprint(i)}
+	}
+}
+
+// Convert a unix timestamp in seconds or milliseconds to time.Time
+func unixTime(timestamp float64) time.Time {
+	if timestamp <= 0 {
+		return time.Time{}
+	} else if timestamp > 1e11 {
+		timestamp /= 1000
+	}
+	return time.Unix(int64(timestamp), int64(timestamp*1e9)%1e9)
+}
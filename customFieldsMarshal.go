@@ -0,0 +1,353 @@
+package ninjarmm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	ninjaTime    = reflect.TypeOf(Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// UnmarshalCustomFields populates the struct pointed by `v` from custom
+// fields, using the `ninja` struct tags.
+//
+// Tag format is `ninja:"fieldName,omitempty,default=value"`:
+//
+//   - pointer fields stay nil when the custom field is unset
+//   - time.Time and ninjarmm.Time fields accept unix timestamps and dates
+//   - time.Duration fields are read as seconds (TIME custom fields)
+//   - slices accept arrays and comma separated strings
+//   - `default=value` is used when the custom field is unset
+//   - untagged struct fields are flattened, their own tags are read from
+//     the same custom fields
+//   - other untagged fields and fields tagged "-" are ignored
+//
+// Usage:
+//
+//	type DeviceInfo struct {
+//		Owner          string     `ninja:"owner"`
+//		WarrantyExpiry *time.Time `ninja:"warrantyExpiry"`
+//		Tier           string     `ninja:"tier,default=Silver"`
+//	}
+//
+//	customFields, err := ninjarmm.GetDeviceCustomFields(deviceID)
+//	if err != nil {
+//		panic(err)
+//	}
+//
+//	var info DeviceInfo
+//	err = ninjarmm.UnmarshalCustomFields(customFields, &info)
+func UnmarshalCustomFields(customFields CustomFields, v interface{}) (err error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("custom fields can only be unmarshaled into a non-nil struct pointer")
+	}
+
+	return unmarshalStruct(customFields, value.Elem())
+}
+
+// Unmarshal populates the struct pointed by `v` from the custom fields.
+//
+// See UnmarshalCustomFields
+func (c CustomFields) Unmarshal(v interface{}) error {
+	return UnmarshalCustomFields(c, v)
+}
+
+// MarshalCustomFields converts a struct (or struct pointer) to custom fields
+// using the `ninja` struct tags (see UnmarshalCustomFields for the format).
+//
+// Nil pointers, slices and maps and zero times are sent as nil (clearing
+// the custom field), other zero values such as false or 0 are sent as they
+// are. Fields tagged `omitempty` are not sent when zero. Times are sent as
+// unix timestamps and durations as seconds.
+func MarshalCustomFields(v interface{}) (customFields CustomFields, err error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		err = fmt.Errorf("custom fields can only be marshaled from a struct, got %T", v)
+		return
+	}
+
+	customFields = make(CustomFields)
+	err = marshalStruct(value, customFields)
+	return
+}
+
+// Options parsed from a `ninja` struct tag
+type customFieldTag struct {
+	name       string
+	omitempty  bool
+	defaultSet bool
+	defaultVal string
+}
+
+// Parse a `ninja` struct tag, ok is false when the field is ignored
+func parseCustomFieldTag(field reflect.StructField) (tag customFieldTag, ok bool) {
+	raw, found := field.Tag.Lookup("ninja")
+	if !found || raw == "-" {
+		return
+	}
+
+	parts := strings.Split(raw, ",")
+	tag.name = parts[0]
+	for _, option := range parts[1:] {
+		switch {
+		case option == "omitempty":
+			tag.omitempty = true
+		case strings.HasPrefix(option, "default="):
+			tag.defaultSet = true
+			tag.defaultVal = strings.TrimPrefix(option, "default=")
+		}
+	}
+
+	return tag, tag.name != ""
+}
+
+// Check if a struct field must be flattened
+func isNestedStruct(field reflect.StructField) bool {
+	_, tagged := field.Tag.Lookup("ninja")
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return !tagged && fieldType.Kind() == reflect.Struct && fieldType != timeType && fieldType != ninjaTime
+}
+
+func unmarshalStruct(customFields CustomFields, value reflect.Value) error {
+	var errs []error
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if isNestedStruct(field) {
+			target := value.Field(i)
+			if target.Kind() == reflect.Pointer {
+				if target.IsNil() {
+					target.Set(reflect.New(field.Type.Elem()))
+				}
+				target = target.Elem()
+			}
+			if err := unmarshalStruct(customFields, target); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		tag, ok := parseCustomFieldTag(field)
+		if !ok {
+			continue
+		}
+
+		raw, found := customFields[tag.name]
+		if (!found || raw == nil) && tag.defaultSet {
+			raw, found = tag.defaultVal, true
+		}
+
+		if !found || raw == nil {
+			// Unset custom field, pointers stay nil and values zero
+			value.Field(i).Set(reflect.Zero(field.Type))
+			continue
+		}
+
+		if err := setCustomFieldValue(value.Field(i), raw); err != nil {
+			errs = append(errs, fmt.Errorf("error unmarshaling custom field '%s': %w", tag.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Convert a raw custom field value to the target type
+func setCustomFieldValue(target reflect.Value, raw interface{}) error {
+	if target.Kind() == reflect.Pointer {
+		elem := reflect.New(target.Type().Elem())
+		if err := setCustomFieldValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	}
+
+	fields := CustomFields{"value": raw}
+
+	switch target.Type() {
+	case timeType:
+		target.Set(reflect.ValueOf(fields.TimeField("value")))
+		return nil
+	case ninjaTime:
+		target.Set(reflect.ValueOf(Time(fields.TimeField("value"))))
+		return nil
+	case durationType:
+		target.SetInt(int64(fields.IntField("value")) * int64(time.Second))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(fields.StringField("value"))
+	case reflect.Bool:
+		target.SetBool(fields.BoolField("value"))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		target.SetInt(int64(fields.IntField("value")))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		target.SetUint(uint64(fields.IntField("value")))
+	case reflect.Float32, reflect.Float64:
+		target.SetFloat(fields.FloatField("value"))
+	case reflect.Slice:
+		var items []interface{}
+		if list, ok := raw.([]interface{}); ok {
+			items = list
+		} else {
+			for _, item := range fields.StringsField("value") {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setCustomFieldValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+	case reflect.Interface, reflect.Map:
+		rawValue := reflect.ValueOf(raw)
+		if !rawValue.Type().AssignableTo(target.Type()) {
+			if rawValue.Type().ConvertibleTo(target.Type()) {
+				rawValue = rawValue.Convert(target.Type())
+			} else {
+				return fmt.Errorf("cannot assign %T to %s", raw, target.Type())
+			}
+		}
+		target.Set(rawValue)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+
+	return nil
+}
+
+func marshalStruct(value reflect.Value, customFields CustomFields) error {
+	var errs []error
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if isNestedStruct(field) {
+			nested := value.Field(i)
+			if nested.Kind() == reflect.Pointer {
+				if nested.IsNil() {
+					continue
+				}
+				nested = nested.Elem()
+			}
+			if err := marshalStruct(nested, customFields); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		tag, ok := parseCustomFieldTag(field)
+		if !ok {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if fieldValue.IsZero() && tag.omitempty {
+			continue
+		}
+
+		if isUnsetValue(fieldValue) {
+			customFields[tag.name] = nil
+			continue
+		}
+
+		raw, err := customFieldRawValue(fieldValue)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error marshaling custom field '%s': %w", tag.name, err))
+			continue
+		}
+		customFields[tag.name] = raw
+	}
+
+	return errors.Join(errs...)
+}
+
+// Nil pointers, slices and maps and zero times have no custom field value,
+// other zero values (false, 0, "") are values
+func isUnsetValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return value.IsNil()
+	}
+
+	switch value.Type() {
+	case timeType, ninjaTime:
+		return value.IsZero()
+	}
+	return false
+}
+
+// Convert a Go value to the raw custom field value sent to the API
+func customFieldRawValue(value reflect.Value) (interface{}, error) {
+	if value.Kind() == reflect.Pointer {
+		return customFieldRawValue(value.Elem())
+	}
+
+	switch value.Type() {
+	case timeType:
+		return value.Interface().(time.Time).Unix(), nil
+	case ninjaTime:
+		return time.Time(value.Interface().(Time)).Unix(), nil
+	case durationType:
+		return int64(value.Interface().(time.Duration) / time.Second), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.String {
+			items := make([]string, value.Len())
+			for i := range items {
+				items[i] = value.Index(i).String()
+			}
+			return items, nil
+		}
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			item, err := customFieldRawValue(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Struct, reflect.Chan, reflect.Func:
+		return nil, fmt.Errorf("unsupported type %s", value.Type())
+	default:
+		return value.Interface(), nil
+	}
+}
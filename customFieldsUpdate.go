@@ -0,0 +1,256 @@
+package ninjarmm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Number of read-modify-write attempts made by UpdateCustomFields when a
+// concurrent change is detected
+const customFieldsUpdateAttempts = 3
+
+// Returned (wrapped in a *CustomFieldsConflictError) by UpdateCustomFields
+// when custom fields were changed by someone else during the update
+var ErrCustomFieldsConflict = errors.New("custom fields changed concurrently")
+
+// CustomFieldsDiff returns only the custom fields changed between `old` and
+// `new`. Fields removed (or set to nil) in `new` are returned as nil, which
+// clears them when sent to the API.
+//
+// Values are compared by their JSON representation, so 1 (int) and 1.0
+// (float64) are equal.
+func CustomFieldsDiff(old, new CustomFields) (diff CustomFields) {
+	diff = make(CustomFields)
+
+	for key, value := range new {
+		if !customFieldEqual(old[key], value) {
+			diff[key] = value
+		}
+	}
+
+	for key, value := range old {
+		if _, found := new[key]; !found && value != nil {
+			diff[key] = nil
+		}
+	}
+
+	return
+}
+
+// Compare two custom field values
+func customFieldEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return string(encodedA) == string(encodedB)
+}
+
+// Read-modify-write the custom fields of a device, an organization or a
+// location.
+//
+// `modify` receives a copy of the current custom fields, only the fields it
+// changes are sent. Before writing, the custom fields are fetched again: if
+// one of the changed fields was modified in the meantime the update is
+// retried with fresh values, and after 3 attempts a
+// *CustomFieldsConflictError is returned.
+//
+// Usage:
+//
+//	err := ninjarmm.UpdateCustomFields(ctx, ninjarmm.DeviceNode(deviceID), func(cf ninjarmm.CustomFields) error {
+//		cf.Set("owner", "john")
+//		cf.Delete("oldField")
+//		return nil
+//	})
+func UpdateCustomFields(ctx context.Context, node CustomFieldsNode, modify func(cf CustomFields) error) (err error) {
+	var conflict *CustomFieldsConflictError
+
+	for attempt := 0; attempt < customFieldsUpdateAttempts; attempt++ {
+		conflict, err = updateCustomFieldsOnce(ctx, node, modify)
+		if err != nil || conflict == nil {
+			return
+		}
+	}
+
+	return conflict
+}
+
+func updateCustomFieldsOnce(ctx context.Context, node CustomFieldsNode, modify func(cf CustomFields) error) (conflict *CustomFieldsConflictError, err error) {
+	snapshot, err := getNodeCustomFields(ctx, node)
+	if err != nil {
+		return
+	}
+
+	// Deep copy so in place changes of slices and maps show in the diff
+	modified := snapshot.clone()
+
+	if err = modify(modified); err != nil {
+		return
+	}
+
+	diff := CustomFieldsDiff(snapshot, modified)
+	if len(diff) == 0 {
+		return
+	}
+
+	current, err := getNodeCustomFields(ctx, node)
+	if err != nil {
+		return
+	}
+
+	for key := range diff {
+		if !customFieldEqual(snapshot[key], current[key]) {
+			if conflict == nil {
+				conflict = &CustomFieldsConflictError{Node: node}
+			}
+			conflict.Keys = append(conflict.Keys, key)
+		}
+	}
+
+	if conflict != nil {
+		sort.Strings(conflict.Keys)
+		return
+	}
+
+	err = setNodeCustomFields(ctx, node, diff)
+	return
+}
+
+// Deep copy of custom fields, nested arrays and objects included
+func (customFields CustomFields) clone() CustomFields {
+	if customFields == nil {
+		return nil
+	}
+
+	clone := make(CustomFields, len(customFields))
+	for key, value := range customFields {
+		clone[key] = cloneCustomFieldValue(value)
+	}
+	return clone
+}
+
+func cloneCustomFieldValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			clone[key] = cloneCustomFieldValue(item)
+		}
+		return clone
+	case CustomFields:
+		return value.clone()
+	case []interface{}:
+		clone := make([]interface{}, len(value))
+		for i, item := range value {
+			clone[i] = cloneCustomFieldValue(item)
+		}
+		return clone
+	case []string:
+		return append([]string(nil), value...)
+	default:
+		return value
+	}
+}
+
+func getNodeCustomFields(ctx context.Context, node CustomFieldsNode) (customFields CustomFields, err error) {
+	err = requestContext(ctx, http.MethodGet, node.customFieldsPath(), nil, &customFields)
+	if err == nil && customFields == nil {
+		customFields = make(CustomFields)
+	}
+	return
+}
+
+func setNodeCustomFields(ctx context.Context, node CustomFieldsNode, customFields CustomFields) (err error) {
+	customFields, err = prepareCustomFields(customFields)
+	if err != nil {
+		return
+	}
+
+	err = requestContext(ctx, http.MethodPatch, node.customFieldsPath(), customFields, nil)
+	return
+}
+
+// Entity holding custom fields: a device, an organization or a location.
+// Use DeviceNode, OrganizationNode or LocationNode.
+type CustomFieldsNode interface {
+	fmt.Stringer
+	customFieldsPath() string
+}
+
+type deviceNode struct {
+	deviceID int
+}
+
+type organizationNode struct {
+	organizationID int
+}
+
+type locationNode struct {
+	organizationID int
+	locationID     int
+}
+
+// DeviceNode returns the custom fields node of a device.
+func DeviceNode(deviceID int) CustomFieldsNode {
+	return deviceNode{deviceID}
+}
+
+// OrganizationNode returns the custom fields node of an organization.
+func OrganizationNode(organizationID int) CustomFieldsNode {
+	return organizationNode{organizationID}
+}
+
+// LocationNode returns the custom fields node of a location.
+func LocationNode(organizationID, locationID int) CustomFieldsNode {
+	return locationNode{organizationID, locationID}
+}
+
+func (node deviceNode) customFieldsPath() string {
+	return fmt.Sprintf("device/%d/custom-fields", node.deviceID)
+}
+
+func (node deviceNode) String() string {
+	return fmt.Sprintf("device %d", node.deviceID)
+}
+
+func (node organizationNode) customFieldsPath() string {
+	return fmt.Sprintf("organization/%d/custom-fields", node.organizationID)
+}
+
+func (node organizationNode) String() string {
+	return fmt.Sprintf("organization %d", node.organizationID)
+}
+
+func (node locationNode) customFieldsPath() string {
+	return fmt.Sprintf("organization/%d/location/%d/custom-fields", node.organizationID, node.locationID)
+}
+
+func (node locationNode) String() string {
+	return fmt.Sprintf("location %d of organization %d", node.locationID, node.organizationID)
+}
+
+// Error returned when custom fields were changed concurrently
+type CustomFieldsConflictError struct {
+	Node CustomFieldsNode
+	Keys []string // Custom fields changed concurrently
+}
+
+func (e *CustomFieldsConflictError) Error() string {
+	return fmt.Sprintf("%s: custom fields changed concurrently: %s", e.Node, strings.Join(e.Keys, ", "))
+}
+
+// Is makes errors.Is(err, ErrCustomFieldsConflict) true.
+func (e *CustomFieldsConflictError) Is(target error) bool {
+	return target == ErrCustomFieldsConflict
+}
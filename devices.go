@@ -1,6 +1,8 @@
 package ninjarmm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -53,6 +55,40 @@ func ListDevices(filter string, detailed bool, after, pageSize int) (devices []D
 	return
 }
 
+// Internal function listing all devices matching a filter, following pages
+func listAllDevices(ctx context.Context, filter string) (devices []Device, err error) {
+	const pageSize = 1000
+	after := 0
+
+	for {
+		urlValues := url.Values{
+			"pageSize": {fmt.Sprint(pageSize)},
+		}
+
+		if filter != "" {
+			urlValues.Set("df", filter)
+		}
+
+		if after != 0 {
+			urlValues.Set("after", fmt.Sprint(after))
+		}
+
+		var page []Device
+		err = requestContext(ctx, http.MethodGet, "devices?"+urlValues.Encode(), nil, &page)
+		if err != nil {
+			return
+		}
+
+		devices = append(devices, page...)
+
+		if len(page) < pageSize {
+			return
+		}
+
+		after = page[len(page)-1].ID
+	}
+}
+
 // Find devices by search string
 //
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/search
@@ -111,10 +147,130 @@ func (device *Device) GetCustomFields() (err error) {
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateNodeAttributeValues
 
 func SetDeviceCustomFields(deviceID int, customFields CustomFields) (err error) {
+	customFields, err = prepareCustomFields(customFields)
+	if err != nil {
+		return
+	}
+
 	err = request(http.MethodPatch, fmt.Sprintf("device/%d/custom-fields", deviceID), customFields, nil)
 	return
 }
 
+// Update device display name, role, policy, location, user data and owner.
+// Only the fields set in `update` are sent.
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateNodeAttributes
+//
+// Usage:
+//
+//	update := ninjarmm.DeviceUpdate{}
+//	update.SetDisplayName("My device").SetLocationID(12)
+//	err := ninjarmm.UpdateDevice(deviceID, update)
+func UpdateDevice(deviceID int, update DeviceUpdate) (err error) {
+	if deviceID == 0 {
+		err = errors.New("device ID required")
+		return
+	} else if update.IsEmpty() {
+		err = errors.New("no field set in device update")
+		return
+	}
+
+	if update.hasAttributes() {
+		err = request(http.MethodPatch, fmt.Sprintf("device/%d", deviceID), update, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	if update.OwnerUID != nil {
+		if *update.OwnerUID == "" {
+			err = RemoveDeviceOwner(deviceID)
+		} else {
+			err = SetDeviceOwner(deviceID, *update.OwnerUID)
+		}
+	}
+
+	return
+}
+
+// Update device display name, role, policy, location, user data and owner.
+//
+// See UpdateDevice
+func (device Device) Update(update DeviceUpdate) (err error) {
+	return UpdateDevice(device.ID, update)
+}
+
+// Assign an owner (end user or contact UID) to a device
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/setDeviceOwner
+func SetDeviceOwner(deviceID int, ownerUID string) (err error) {
+	if ownerUID == "" {
+		err = errors.New("owner UID required")
+	} else {
+		err = request(http.MethodPost, fmt.Sprintf("device/%d/owner/%s", deviceID, url.PathEscape(ownerUID)), nil, nil)
+	}
+	return
+}
+
+// Remove the owner of a device
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/removeDeviceOwner
+func RemoveDeviceOwner(deviceID int) (err error) {
+	err = request(http.MethodDelete, fmt.Sprintf("device/%d/owner", deviceID), nil, nil)
+	return
+}
+
+// Fields to change on a device, nil fields are left untouched.
+// Use the setters to build it.
+type DeviceUpdate struct {
+	DisplayName *string      `json:"displayName,omitempty"`
+	NodeRoleID  *int         `json:"nodeRoleId,omitempty"`
+	PolicyID    *int         `json:"policyId,omitempty"`
+	LocationID  *int         `json:"locationId,omitempty"`
+	UserData    CustomFields `json:"userData,omitempty"`
+	OwnerUID    *string      `json:"-"` // Empty string removes the owner
+}
+
+// SetDisplayName sets the new device display name.
+func (update *DeviceUpdate) SetDisplayName(displayName string) *DeviceUpdate {
+	update.DisplayName = &displayName
+	return update
+}
+
+// SetNodeRoleID sets the new device role.
+func (update *DeviceUpdate) SetNodeRoleID(nodeRoleID int) *DeviceUpdate {
+	update.NodeRoleID = &nodeRoleID
+	return update
+}
+
+// SetPolicyID sets the new device policy.
+func (update *DeviceUpdate) SetPolicyID(policyID int) *DeviceUpdate {
+	update.PolicyID = &policyID
+	return update
+}
+
+// SetLocationID sets the new device location.
+func (update *DeviceUpdate) SetLocationID(locationID int) *DeviceUpdate {
+	update.LocationID = &locationID
+	return update
+}
+
+// SetOwnerUID sets the new device owner, an empty UID removes the owner.
+func (update *DeviceUpdate) SetOwnerUID(ownerUID string) *DeviceUpdate {
+	update.OwnerUID = &ownerUID
+	return update
+}
+
+// IsEmpty returns true if no field is set.
+func (update DeviceUpdate) IsEmpty() bool {
+	return !update.hasAttributes() && update.OwnerUID == nil
+}
+
+// Check if the update needs the PATCH device endpoint
+func (update DeviceUpdate) hasAttributes() bool {
+	return update.DisplayName != nil || update.NodeRoleID != nil || update.PolicyID != nil || update.LocationID != nil || update.UserData != nil
+}
+
 type Device struct {
 	ID             int            `json:"id"`
 	ParentDeviceID int            `json:"parentDeviceId"`
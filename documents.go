@@ -19,7 +19,11 @@ func GetOrganizationDocuments(organizationID int) (documents []Document, err err
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateOrganizationDocument
 
 func UpdateOrganizationDocument(organizationID int, document Document) (err error) {
-	err = request(http.MethodPost, fmt.Sprintf("organization/%d/document/%d", organizationID, document.ClientDocumentID), nil, nil)
+	if document.ClientDocumentID == 0 {
+		err = fmt.Errorf("document ID required")
+	} else {
+		err = request(http.MethodPost, fmt.Sprintf("organization/%d/document/%d", organizationID, document.ClientDocumentID), document, nil)
+	}
 	return
 }
 
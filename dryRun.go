@@ -0,0 +1,155 @@
+package ninjarmm
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// Dry-run mode, see EnableDryRun
+	dryRun   *dryRunConfig
+	dryRunMu sync.RWMutex
+)
+
+// Enable the dry-run mode: every mutating request is validated (payload
+// encoding), given to `logger` with its full payload and answered with a
+// synthetic success, nothing is sent to the API. Reads still run: GET
+// requests and read-only POST requests such as board runs
+// (ListTicketsByBoard).
+//
+// When the expected response has the same shape as the payload (e.g.
+// CreateLocation), the payload is echoed as response, otherwise the
+// response is left empty. Resources created with POST get a negative
+// placeholder ID (-1, -2...) so requests depending on them, such as the
+// locations of an organization created by OrganizationsPlan.Apply, show
+// which resource they target.
+//
+// A nil `logger` writes the requests to the standard logger.
+//
+// Usage:
+//
+//	ninjarmm.EnableDryRun(func(req ninjarmm.DryRunRequest) {
+//		fmt.Printf("%s %s\n%s\n", req.Method, req.Path, req.Payload)
+//	})
+//	defer ninjarmm.DisableDryRun()
+func EnableDryRun(logger func(request DryRunRequest)) {
+	if logger == nil {
+		logger = func(request DryRunRequest) {
+			log.Printf("[ninjarmm dry-run] %s %s %s", request.Method, request.Path, request.Payload)
+		}
+	}
+
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	dryRun = &dryRunConfig{logger: logger}
+}
+
+// Disable the dry-run mode, requests are sent again.
+func DisableDryRun() {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	dryRun = nil
+}
+
+// Returns true if the dry-run mode is enabled
+func DryRunEnabled() bool {
+	dryRunMu.RLock()
+	defer dryRunMu.RUnlock()
+	return dryRun != nil
+}
+
+// Intercept a request in dry-run mode, returns false if the request must be
+// sent to the API
+func interceptDryRun(method, path string, payload []byte, response interface{}) bool {
+	if isReadOnlyRequest(method, path) {
+		return false
+	}
+
+	dryRunMu.RLock()
+	config := dryRun
+	dryRunMu.RUnlock()
+
+	if config == nil {
+		return false
+	}
+
+	config.logger(DryRunRequest{
+		Method:  method,
+		Path:    path,
+		Payload: json.RawMessage(payload),
+	})
+
+	// Plausible echo of the input, ignored when shapes don't match
+	if response != nil && len(payload) > 0 {
+		_ = json.Unmarshal(payload, response)
+	}
+
+	if response != nil && method == http.MethodPost {
+		placeholder, _ := json.Marshal(map[string]int64{"id": -config.created.Add(1)})
+		_ = json.Unmarshal(placeholder, response)
+	}
+
+	return true
+}
+
+// Routes of the POST requests which read data, "{id}" matches a numeric
+// path segment
+var readOnlyPostRoutes = []string{
+	"ticketing/trigger/board/{id}/run",
+}
+
+// Returns true for GET requests and read-only POST requests, which run in
+// dry-run mode and are not audited
+func isReadOnlyRequest(method, path string) bool {
+	switch method {
+	case http.MethodGet:
+		return true
+	case http.MethodPost:
+	default:
+		return false
+	}
+
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+
+	for _, route := range readOnlyPostRoutes {
+		if matchRoute(strings.Split(route, "/"), segments) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRoute(route, segments []string) bool {
+	if len(route) != len(segments) {
+		return false
+	}
+
+	for i, segment := range route {
+		if segment == "{id}" {
+			if _, err := strconv.Atoi(segments[i]); err != nil {
+				return false
+			}
+		} else if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type dryRunConfig struct {
+	logger  func(request DryRunRequest)
+	created atomic.Int64 // Placeholder IDs given
+}
+
+// Request intercepted in dry-run mode
+type DryRunRequest struct {
+	Method  string
+	Path    string          // Path relative to the API base URL, with query string
+	Payload json.RawMessage // JSON payload, empty when no payload
+}
@@ -0,0 +1,264 @@
+package ninjarmm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Returns device physical disks
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceDisks
+func GetDeviceDisks(deviceID int) (disks []Disk, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/disks", deviceID), nil, &disks)
+	return
+}
+
+// Returns device disk volumes
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceVolumes
+func GetDeviceVolumes(deviceID int, includeBitLocker bool) (volumes []DiskVolumes, err error) {
+	values := url.Values{}
+
+	if includeBitLocker {
+		values.Set("include", "bl")
+	}
+
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/volumes?%s", deviceID, values.Encode()), nil, &volumes)
+	return
+}
+
+// Returns device processors
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceProcessors
+func GetDeviceProcessors(deviceID int) (processors []ProcessorInfo, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/processors", deviceID), nil, &processors)
+	return
+}
+
+// Returns device network interfaces
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceNetworkInterfaces
+func GetDeviceNetworkInterfaces(deviceID int) (networkInterfaces []NetworkInterface, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/network-interfaces", deviceID), nil, &networkInterfaces)
+	return
+}
+
+// Returns device Windows services, optionally filtered by name and state
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceWindowsServices
+func GetDeviceWindowsServices(deviceID int, name string, state ServiceState) (services []WindowsService, err error) {
+	values := url.Values{}
+
+	if name != "" {
+		values.Set("name", name)
+	}
+
+	if state != "" {
+		values.Set("state", string(state))
+	}
+
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/windows-services?%s", deviceID, values.Encode()), nil, &services)
+	return
+}
+
+// Returns device installed software
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getApplications
+func GetDeviceSoftware(deviceID int) (software []Software, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/software", deviceID), nil, &software)
+	return
+}
+
+// Returns device pending, failed and rejected OS patches
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceOsPatches
+func GetDeviceOSPatches(deviceID int, status PatchStatus, patchType string, severity string) (patches []OSPatch, err error) {
+	values := url.Values{}
+
+	if status != "" {
+		values.Set("status", string(status))
+	}
+
+	if patchType != "" {
+		values.Set("type", patchType)
+	}
+
+	if severity != "" {
+		values.Set("severity", severity)
+	}
+
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/os-patches?%s", deviceID, values.Encode()), nil, &patches)
+	return
+}
+
+// Returns device pending, failed and rejected software patches
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceSoftwarePatches
+func GetDeviceSoftwarePatches(deviceID int, status PatchStatus, productIdentifier string, impact string) (patches []SoftwarePatch, err error) {
+	values := url.Values{}
+
+	if status != "" {
+		values.Set("status", string(status))
+	}
+
+	if productIdentifier != "" {
+		values.Set("productIdentifier", productIdentifier)
+	}
+
+	if impact != "" {
+		values.Set("impact", impact)
+	}
+
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/software-patches?%s", deviceID, values.Encode()), nil, &patches)
+	return
+}
+
+// Returns the last user logged on the device
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getLastLoggedOnUser
+func GetDeviceLastLoggedOnUser(deviceID int) (user LoggedOnUser, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/last-logged-on-user", deviceID), nil, &user)
+	return
+}
+
+// Returns currently running (active) jobs for the device
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceJobs
+func GetDeviceActiveJobs(deviceID int) (jobs []DeviceJob, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/jobs", deviceID), nil, &jobs)
+	return
+}
+
+// Returns the policy sections overridden on the device
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDevicePolicyOverrides
+func GetDevicePolicyOverrides(deviceID int) (overrides PolicyOverrides, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/policy/overrides", deviceID), nil, &overrides)
+	return
+}
+
+type Disk struct {
+	PhysicalName   string `json:"physicalName"`
+	Name           string `json:"name"`
+	Caption        string `json:"caption"`
+	Description    string `json:"description"`
+	Model          string `json:"model"`
+	Manufacturer   string `json:"manufacturer"`
+	InterfaceType  string `json:"interfaceType"`
+	MediaType      string `json:"mediaType"`
+	SerialNumber   string `json:"serialNumber"`
+	SmartCapable   bool   `json:"smartCapable"`
+	Status         string `json:"status"`
+	Size           int    `json:"size"`
+	Partitions     int    `json:"partitionCount"`
+	BytesPerSector int    `json:"bytesPerSector"`
+	DeviceID       int    `json:"deviceId"`
+	Timestamp      Time   `json:"timestamp"`
+}
+
+type NetworkInterface struct {
+	AdapterName    string   `json:"adapterName"`
+	InterfaceName  string   `json:"interfaceName"`
+	InterfaceIndex int      `json:"interfaceIndex"`
+	Manufacturer   string   `json:"manufacturer"`
+	Status         string   `json:"status"`
+	LinkSpeed      int      `json:"linkSpeed"`
+	DHCPEnabled    bool     `json:"dhcpEnabled"`
+	MACAddress     []string `json:"macAddress"`
+	IPAddress      []string `json:"ipAddress"`
+	DefaultGateway string   `json:"defaultGateway"`
+	DNSServers     []string `json:"dnsServers"`
+	DeviceID       int      `json:"deviceId"`
+	Timestamp      Time     `json:"timestamp"`
+}
+
+type WindowsService struct {
+	Name        string       `json:"name"`
+	DisplayName string       `json:"displayName"`
+	Description string       `json:"description"`
+	ServiceType string       `json:"serviceType"`
+	StartType   string       `json:"startType"`
+	State       ServiceState `json:"state"`
+	UserName    string       `json:"userName"`
+	DeviceID    int          `json:"deviceId"`
+	Timestamp   Time         `json:"timestamp"`
+}
+
+type ServiceState string
+
+const (
+	ServiceStateUnknown         ServiceState = "UNKNOWN"
+	ServiceStateStopped         ServiceState = "STOPPED"
+	ServiceStateStartPending    ServiceState = "START_PENDING"
+	ServiceStateRunning         ServiceState = "RUNNING"
+	ServiceStateStopPending     ServiceState = "STOP_PENDING"
+	ServiceStatePausePending    ServiceState = "PAUSE_PENDING"
+	ServiceStatePaused          ServiceState = "PAUSED"
+	ServiceStateContinuePending ServiceState = "CONTINUE_PENDING"
+)
+
+type OSPatch struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	KBNumber    string      `json:"kbNumber"`
+	Severity    string      `json:"severity"`
+	Status      PatchStatus `json:"status"`
+	Type        string      `json:"type"`
+	InstalledAt Time        `json:"installedAt"`
+	DeviceID    int         `json:"deviceId"`
+	Timestamp   Time        `json:"timestamp"`
+}
+
+type SoftwarePatch struct {
+	ID                string      `json:"id"`
+	ProductIdentifier string      `json:"productIdentifier"`
+	Title             string      `json:"title"`
+	Impact            string      `json:"impact"`
+	Status            PatchStatus `json:"status"`
+	Type              string      `json:"type"`
+	InstalledAt       Time        `json:"installedAt"`
+	DeviceID          int         `json:"deviceId"`
+	Timestamp         Time        `json:"timestamp"`
+}
+
+type PatchStatus string
+
+const (
+	PatchStatusManual   PatchStatus = "MANUAL"
+	PatchStatusApproved PatchStatus = "APPROVED"
+	PatchStatusFailed   PatchStatus = "FAILED"
+	PatchStatusRejected PatchStatus = "REJECTED"
+	PatchStatusPending  PatchStatus = "PENDING"
+)
+
+type LoggedOnUser struct {
+	UserName  string `json:"userName"`
+	LogonTime Time   `json:"logonTime"`
+	DeviceID  int    `json:"deviceId"`
+	Timestamp Time   `json:"timestamp"`
+}
+
+type DeviceJob struct {
+	UID             string `json:"uid"`
+	DeviceID        int    `json:"deviceId"`
+	Message         string `json:"message"`
+	CreateTime      Time   `json:"createTime"`
+	UpdateTime      Time   `json:"updateTime"`
+	SourceType      string `json:"sourceType"`
+	SourceConfigUID string `json:"sourceConfigUid"`
+	SourceName      string `json:"sourceName"`
+	Subject         string `json:"subject"`
+	UserID          int    `json:"userId"`
+	JobStatus       string `json:"jobStatus"`
+	JobResult       string `json:"jobResult"`
+	JobType         string `json:"jobType"`
+	Data            any    `json:"data"`
+}
+
+type PolicyOverrides struct {
+	PolicyID  int      `json:"policyId"`
+	Overrides []string `json:"overrides"` // Overridden policy sections
+	DeviceID  int      `json:"deviceId"`
+	Timestamp Time     `json:"timestamp"`
+}
@@ -70,6 +70,11 @@ func (location *Location) GetCustomFields() (err error) {
 //
 // See https://app.ninjarmm.com/apidocs-beta/core-resources/operations/updateNodeAttributeValues_2
 func SetLocationCustomFields(organizationID, locationID int, customFields CustomFields) (err error) {
+	customFields, err = prepareCustomFields(customFields)
+	if err != nil {
+		return
+	}
+
 	err = request(http.MethodPatch, fmt.Sprintf("organization/%d/location/%d/custom-fields", organizationID, locationID), customFields, nil)
 	return
 }
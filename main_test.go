@@ -13,11 +13,17 @@ Else you can set the environment variables NINJARMM_CLIENT_ID and NINJARMM_CLIEN
 package ninjarmm
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 // ⚠️ Use with Caution ⚠️
@@ -188,3 +194,1185 @@ func envVars() (clientID, clientSecret string, err error) {
 
 	return
 }
+
+func TestScriptRunMatches(t *testing.T) {
+	run := ScriptRun{
+		DeviceID:       1,
+		Request:        ScriptRunRequest{Type: ScriptTypeScript, ID: 42, UID: "script-uid"},
+		lastActivityID: 100,
+	}
+
+	activity := Activity{
+		ID:              101,
+		DeviceID:        1,
+		SourceConfigUID: "script-uid",
+		Data:            map[string]interface{}{"exitCode": float64(2), "output": "done"},
+		ActivityType:    ActivityTypeScripting,
+		ActivityResult:  ActivityResultFailure,
+	}
+
+	if !run.matches(activity) {
+		t.Fatal("expected activity to match script run")
+	}
+
+	result := newScriptResult(activity)
+	if result.ExitCode != 2 || result.Output != "done" || result.Succeeded() {
+		t.Errorf("unexpected script result: %+v", result)
+	}
+
+	activity.ID = 99
+	if run.matches(activity) {
+		t.Error("activity older than the run must not match")
+	}
+
+	activity.ID = 102
+	activity.Data = map[string]interface{}{"resultCode": float64(-3)}
+	if result := newScriptResult(activity); result.ExitCode != -3 {
+		t.Errorf("unexpected exit code %d", result.ExitCode)
+	}
+
+	// Without job UID nor script UID any completion could be another run
+	uncorrelated := ScriptRun{DeviceID: 1, Request: ScriptRunRequest{Type: ScriptTypeScript, ID: 42}, lastActivityID: 100}
+	if uncorrelated.matches(activity) {
+		t.Error("uncorrelated run must not match")
+	}
+
+	if _, err := uncorrelated.Wait(context.Background()); !errors.Is(err, ErrScriptRunNotCorrelated) {
+		t.Errorf("expected ErrScriptRunNotCorrelated, got %v", err)
+	}
+}
+
+func TestDeviceUpdateJSON(t *testing.T) {
+	update := DeviceUpdate{}
+	update.SetDisplayName("device").SetLocationID(0).SetOwnerUID("owner")
+
+	data, err := json.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"displayName":"device","locationId":0}` {
+		t.Errorf("unexpected device update payload: %s", data)
+	}
+}
+
+func TestInventoryDecode(t *testing.T) {
+	var processors []ProcessorInfo
+	err := json.Unmarshal([]byte(`[{"name": "Intel(R) Core(TM) i5-8500", "manufacturer": "GenuineIntel", "architecture": "x64", "maxClockSpeed": 3000, "clockSpeed": 2904, "numCores": 6, "numLogicalCores": 12, "deviceId": 7, "timestamp": 1700666991}]`), &processors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(processors) != 1 || processors[0].Name != "Intel(R) Core(TM) i5-8500" || processors[0].Manufacturer != "GenuineIntel" || processors[0].ClockSpeed != 2904 || processors[0].NumCores != 6 || processors[0].NumLogicalCores != 12 || processors[0].DeviceID != 7 || time.Time(processors[0].Timestamp).Unix() != 1700666991 {
+		t.Errorf("unexpected processors %+v", processors)
+	}
+
+	var disk Disk
+	err = json.Unmarshal([]byte(`{"name": "Disk 0", "model": "Samsung SSD 970", "mediaType": "SSD", "smartCapable": true, "size": 500107862016, "partitionCount": 3, "bytesPerSector": 512, "deviceId": 7}`), &disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if disk.Model != "Samsung SSD 970" || !disk.SmartCapable || disk.Size != 500107862016 || disk.Partitions != 3 || disk.BytesPerSector != 512 {
+		t.Errorf("unexpected disk %+v", disk)
+	}
+
+	var networkInterface NetworkInterface
+	err = json.Unmarshal([]byte(`{"adapterName": "Intel(R) Ethernet", "interfaceIndex": 12, "linkSpeed": 1000000000, "dhcpEnabled": true, "macAddress": ["00:11:22:33:44:55"], "ipAddress": ["10.0.0.12", "fe80::1"], "dnsServers": ["10.0.0.1"], "defaultGateway": "10.0.0.1"}`), &networkInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if networkInterface.InterfaceIndex != 12 || !networkInterface.DHCPEnabled || len(networkInterface.IPAddress) != 2 || networkInterface.MACAddress[0] != "00:11:22:33:44:55" || networkInterface.DNSServers[0] != "10.0.0.1" {
+		t.Errorf("unexpected network interface %+v", networkInterface)
+	}
+
+	var services []WindowsService
+	err = json.Unmarshal([]byte(`[{"name": "Spooler", "displayName": "Print Spooler", "startType": "AUTO_START", "state": "RUNNING", "userName": "LocalSystem"}]`), &services)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(services) != 1 || services[0].State != ServiceStateRunning || services[0].DisplayName != "Print Spooler" {
+		t.Errorf("unexpected services %+v", services)
+	}
+
+	var patches []OSPatch
+	err = json.Unmarshal([]byte(`[{"id": "a1b2", "name": "2024-01 Cumulative Update", "kbNumber": "KB5034122", "severity": "CRITICAL", "status": "FAILED", "installedAt": 0}]`), &patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(patches) != 1 || patches[0].Status != PatchStatusFailed || patches[0].KBNumber != "KB5034122" || !time.Time(patches[0].InstalledAt).IsZero() {
+		t.Errorf("unexpected OS patches %+v", patches)
+	}
+
+	var user LoggedOnUser
+	err = json.Unmarshal([]byte(`{"userName": "CORP\\jdoe", "logonTime": 1700666991.5, "deviceId": 7}`), &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.UserName != `CORP\jdoe` || time.Time(user.LogonTime).Unix() != 1700666991 {
+		t.Errorf("unexpected logged on user %+v", user)
+	}
+
+	var jobs []DeviceJob
+	err = json.Unmarshal([]byte(`[{"uid": "job-1", "deviceId": 7, "jobStatus": "IN_PROCESS", "jobType": "SCRIPTING", "sourceConfigUid": "script-uid", "data": {"message": "running"}}]`), &jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 1 || jobs[0].UID != "job-1" || jobs[0].SourceConfigUID != "script-uid" || jobs[0].Data == nil {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+
+	var overrides PolicyOverrides
+	err = json.Unmarshal([]byte(`{"policyId": 3, "overrides": ["antivirus", "patchManagement"], "deviceId": 7}`), &overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if overrides.PolicyID != 3 || len(overrides.Overrides) != 2 {
+		t.Errorf("unexpected policy overrides %+v", overrides)
+	}
+}
+
+func TestQueryOptionsQueryString(t *testing.T) {
+	options := QueryOptions{
+		DeviceFilter: "org = 1",
+		PageSize:     50,
+		Fields:       []string{"a", "b"},
+		Extra:        url.Values{"status": {"FAILED"}},
+	}
+
+	if got, want := options.queryString(), "df=org+%3D+1&fields=a%2Cb&pageSize=50&status=FAILED"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCustomFieldDefinitions(t *testing.T) {
+	definitions := CustomFieldDefinitions{
+		{Name: "warrantyExpiry", Type: CustomFieldTypeDate},
+		{Name: "tier", Type: CustomFieldTypeDropdown, Content: CustomFieldContent{
+			Values: []CustomFieldOption{{ID: "guid-gold", Name: "Gold"}, {ID: "guid-silver", Name: "Silver"}},
+		}},
+		{Name: "features", Type: CustomFieldTypeMultiSelect, Content: CustomFieldContent{
+			Values: []CustomFieldOption{{ID: "guid-a", Name: "A"}, {ID: "guid-b", Name: "B"}},
+		}},
+		{Name: "contact", Type: CustomFieldTypeEmail},
+	}
+
+	decoded, err := definitions.Decode(CustomFields{
+		"warrantyExpiry": float64(1700000000),
+		"tier":           "guid-gold",
+		"features":       []interface{}{"guid-b", "guid-a"},
+		"unknown":        "kept",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.TimeField("warrantyExpiry").Unix() != 1700000000 || decoded["tier"] != "Gold" || decoded["unknown"] != "kept" {
+		t.Errorf("unexpected decoded fields: %+v", decoded)
+	}
+
+	if features := decoded.StringsField("features"); len(features) != 2 || features[0] != "B" {
+		t.Errorf("unexpected decoded multi-select: %+v", features)
+	}
+
+	encoded, err := definitions.Encode(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if encoded["tier"] != "guid-gold" || encoded["warrantyExpiry"] != int64(1700000000) {
+		t.Errorf("unexpected encoded fields: %+v", encoded)
+	}
+
+	if err := definitions.Validate(CustomFields{"tier": "Bronze", "contact": "not an email"}); err == nil {
+		t.Error("expected validation errors")
+	}
+}
+
+func TestCustomFieldsMarshal(t *testing.T) {
+	type Contract struct {
+		Reference string `ninja:"contractRef"`
+	}
+
+	type DeviceInfo struct {
+		Owner          string     `ninja:"owner"`
+		WarrantyExpiry *time.Time `ninja:"warrantyExpiry,omitempty"`
+		Tier           string     `ninja:"tier,default=Silver"`
+		Features       []string   `ninja:"features,omitempty"`
+		Managed        *bool      `ninja:"managed"`
+		Enabled        bool       `ninja:"enabled"`
+		Seats          int        `ninja:"seats"`
+		Notes          []string   `ninja:"notes"`
+		Ignored        string     `ninja:"-"`
+		Contract
+	}
+
+	var info DeviceInfo
+	err := UnmarshalCustomFields(CustomFields{
+		"owner":          "john",
+		"warrantyExpiry": float64(1700000000),
+		"features":       "a, b",
+		"contractRef":    "C-42",
+	}, &info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Owner != "john" || info.WarrantyExpiry == nil || info.WarrantyExpiry.Unix() != 1700000000 ||
+		info.Tier != "Silver" || len(info.Features) != 2 || info.Managed != nil || info.Reference != "C-42" {
+		t.Errorf("unexpected unmarshaled struct: %+v", info)
+	}
+
+	info.WarrantyExpiry = nil
+	customFields, err := MarshalCustomFields(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := customFields["warrantyExpiry"]; ok {
+		t.Error("omitempty nil pointer must not be marshaled")
+	}
+
+	if value, ok := customFields["managed"]; !ok || value != nil {
+		t.Error("nil pointer without omitempty must clear the custom field")
+	}
+
+	if customFields["contractRef"] != "C-42" || customFields["tier"] != "Silver" {
+		t.Errorf("unexpected marshaled custom fields: %+v", customFields)
+	}
+
+	if value, ok := customFields["enabled"]; !ok || value != false {
+		t.Errorf("false must be marshaled as false, got %v", value)
+	}
+
+	if value, ok := customFields["seats"]; !ok || value != int64(0) {
+		t.Errorf("0 must be marshaled as 0, got %#v", value)
+	}
+
+	if value, ok := customFields["notes"]; !ok || value != nil {
+		t.Errorf("nil slice without omitempty must clear the custom field, got %v", value)
+	}
+}
+
+func TestCustomFieldsDiff(t *testing.T) {
+	old := CustomFields{"same": float64(1), "changed": "a", "removed": "x", "nilled": "y", "wasNil": nil}
+	new := CustomFields{"same": 1, "changed": "b", "nilled": nil, "added": true}
+
+	diff := CustomFieldsDiff(old, new)
+
+	expected := CustomFields{"changed": "b", "removed": nil, "nilled": nil, "added": true}
+	if len(diff) != len(expected) {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+	for key, value := range expected {
+		if got, ok := diff[key]; !ok || got != value {
+			t.Errorf("diff[%q] = %v, want %v", key, got, value)
+		}
+	}
+
+	// Changes made in place on a copy must show in the diff
+	snapshot := CustomFields{"tags": []interface{}{"a"}, "address": map[string]interface{}{"city": "Paris"}}
+	modified := snapshot.clone()
+	modified["tags"].([]interface{})[0] = "b"
+	modified["address"].(map[string]interface{})["city"] = "Lyon"
+
+	if diff := CustomFieldsDiff(snapshot, modified); len(diff) != 2 {
+		t.Errorf("in place changes missing from diff: %+v", diff)
+	}
+}
+
+func TestBulkDevicesReport(t *testing.T) {
+	devices := []Device{{ID: 3, DisplayName: "c"}, {ID: 1, DisplayName: "a"}, {ID: 2, DisplayName: "b"}}
+
+	report := bulkDevices(context.Background(), devices, bulkOptions{concurrency: 2}, func(ctx context.Context, device Device) (result BulkDeviceResult) {
+		switch device.ID {
+		case 2:
+			result.Err = errors.New("boom")
+		case 3:
+			result.Status = BulkStatusDryRun
+			result.Changes = CustomFields{"a": 1}
+		}
+		return
+	})
+
+	if len(report.Results) != 3 || report.Results[0].DeviceID != 1 || report.Results[0].Status != BulkStatusUpdated {
+		t.Fatalf("unexpected report: %+v", report.Results)
+	}
+
+	if failed := report.Failed(); len(failed) != 1 || failed[0].DeviceID != 2 || failed[0].Status != BulkStatusFailed {
+		t.Errorf("unexpected failed results: %+v", failed)
+	}
+
+	var buffer strings.Builder
+	if err := report.WriteCSV(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "device_id,device_name,status,changes,error\n1,a,UPDATED,,\n2,b,FAILED,,boom\n3,c,DRY_RUN,\"{\"\"a\"\":1}\",\n"
+	if buffer.String() != expected {
+		t.Errorf("unexpected CSV:\n%s", buffer.String())
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var intercepted []DryRunRequest
+	EnableDryRun(func(request DryRunRequest) {
+		intercepted = append(intercepted, request)
+	})
+	defer DisableDryRun()
+
+	location, err := CreateLocation(1, Location{Name: "Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location.Name != "Paris" || location.ID != -1 {
+		t.Errorf("expected payload echo with placeholder ID, got %+v", location)
+	}
+
+	if _, err := UpdateOrganizationPolicies(1, []OrganizationPolicyItem{{NodeRoleID: 1, PolicyID: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(intercepted) != 2 || intercepted[0].Method != "POST" || intercepted[0].Path != "organization/1/locations" || string(intercepted[0].Payload) != `{"name":"Paris"}` {
+		t.Errorf("unexpected intercepted requests: %+v", intercepted)
+	}
+
+	// Board runs read tickets, they are sent to the API
+	if interceptDryRun(http.MethodPost, "ticketing/trigger/board/4/run", []byte(`{}`), nil) || len(intercepted) != 2 {
+		t.Error("board runs must not be intercepted")
+	}
+
+	if !isReadOnlyRequest(http.MethodGet, "devices") || isReadOnlyRequest(http.MethodPost, "ticketing/trigger/board/x/run") || isReadOnlyRequest(http.MethodPut, "ticketing/trigger/board/4/run") {
+		t.Error("unexpected read-only requests")
+	}
+}
+
+type memoryAuditSink []AuditEntry
+
+func (sink *memoryAuditSink) Record(entry AuditEntry) error {
+	*sink = append(*sink, entry)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	sink := &memoryAuditSink{}
+	EnableAudit(AuditOptions{Sink: sink})
+	defer DisableAudit()
+	EnableDryRun(func(DryRunRequest) {})
+	defer DisableDryRun()
+
+	ctx := WithAuditActor(context.Background(), "jdoe", "CHG-1234")
+	err := setNodeCustomFields(ctx, LocationNode(12, 3), CustomFields{"wifiPassword": "x", "owner": "john"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*sink) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(*sink))
+	}
+
+	entry := (*sink)[0]
+	if entry.Operation != "updateLocationCustomFields" || entry.Route != "PATCH organization/{organizationId}/location/{locationId}/custom-fields" ||
+		entry.Targets["organizationId"] != "12" || entry.Targets["locationId"] != "3" ||
+		entry.Outcome != AuditOutcomeDryRun || entry.Actor != "jdoe" || entry.Reason != "CHG-1234" {
+		t.Errorf("unexpected audit entry: %+v", entry)
+	}
+
+	EnableAudit(AuditOptions{Sink: sink, RedactKeys: []string{"wifiPassword"}})
+	*sink = nil
+	SetLocationCustomFields(12, 3, CustomFields{"wifiPassword": "x"})
+	if len(*sink) != 1 || string((*sink)[0].Payload) != `{"wifiPassword":"[REDACTED]"}` {
+		t.Errorf("expected redacted payload, got %+v", *sink)
+	}
+
+	// Secure custom fields are redacted whatever their name
+	UseCustomFieldDefinitions(CustomFieldDefinitions{{Name: "bitlockerKey", Type: CustomFieldTypeSecure}, {Name: "owner", Type: CustomFieldTypeText}})
+	defer UseCustomFieldDefinitions(nil)
+	*sink = nil
+	SetDeviceCustomFields(5, CustomFields{"bitlockerKey": "123-456", "owner": "john"})
+	if len(*sink) != 1 || string((*sink)[0].Payload) != `{"bitlockerKey":"[REDACTED]","owner":"john"}` {
+		t.Errorf("expected redacted secure custom field, got %+v", *sink)
+	}
+
+	// String identifiers are kept as targets
+	*sink = nil
+	SetDeviceOwner(5, "a1b2-c3")
+	DeleteEndUser(8)
+	if len(*sink) != 2 || (*sink)[0].Operation != "setDeviceOwner" || (*sink)[0].Targets["deviceId"] != "5" || (*sink)[0].Targets["ownerUid"] != "a1b2-c3" ||
+		(*sink)[1].Operation != "deleteEndUser" || (*sink)[1].Targets["userId"] != "8" {
+		t.Errorf("unexpected audit entries: %+v", *sink)
+	}
+
+	// Routes outside of the operation table are named by their route
+	if operation, route, targets := auditOperation(http.MethodPost, "organization/1/end-users/2/policies/3"); operation != route ||
+		route != "POST organization/{organizationId}/end-users/{endUserId}/policies/{policyId}" || targets["policyId"] != "3" {
+		t.Errorf("unexpected fallback operation: %s %s %v", operation, route, targets)
+	}
+
+	// Board runs read tickets, they are not audited
+	*sink = nil
+	recordAudit(context.Background(), http.MethodPost, "ticketing/trigger/board/4/run", []byte(`{}`), false, nil)
+	if len(*sink) != 0 {
+		t.Errorf("board runs must not be audited, got %+v", *sink)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var changes []string
+	EnableCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2, OpenTimeout: 10 * time.Millisecond, OnStateChange: func(family string, from, to CircuitState) {
+		changes = append(changes, fmt.Sprintf("%s %s->%s", family, from, to))
+	}})
+	defer DisableCircuitBreaker()
+
+	for path, expected := range map[string]string{
+		"devices-detailed?df=org":          "device",
+		"queries/processor-report":         "query",
+		"policies":                         "policy",
+		"policy/3/condition/custom-fields": "policy",
+		"activities":                       "activity",
+		"ticketing/trigger/boards":         "ticketing",
+	} {
+		if family := endpointFamily(path); family != expected {
+			t.Errorf("endpoint family of %q: got %q, want %q", path, family, expected)
+		}
+	}
+
+	// Sent while closed, completes once the circuit is half-open
+	late, err := allowCircuit("device/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		done, err := allowCircuit("device/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		done(&APIError{StatusCode: http.StatusBadGateway})
+	}
+
+	if _, err := allowCircuit("device/1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+
+	if _, err := allowCircuit("organization/1"); err != nil {
+		t.Errorf("other families must not be affected: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	done, err := allowCircuit("device/1")
+	if err != nil || CircuitBreakerState("device") != CircuitHalfOpen {
+		t.Fatalf("expected half-open probe, got %v", err)
+	}
+
+	late(nil)
+	if state := CircuitBreakerState("device"); state != CircuitHalfOpen {
+		t.Errorf("late completion must not count as a probe, got %s", state)
+	}
+
+	if _, err := allowCircuit("device/1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("probe slot must still be taken, got %v", err)
+	}
+
+	// A canceled probe only frees its slot
+	done(fmt.Errorf("error sending request: %w", context.Canceled))
+	if state := CircuitBreakerState("device"); state != CircuitHalfOpen {
+		t.Errorf("canceled probe must leave the circuit half-open, got %s", state)
+	}
+
+	done, err = allowCircuit("device/1")
+	if err != nil {
+		t.Fatalf("expected the probe slot to be free, got %v", err)
+	}
+
+	done(&APIError{StatusCode: http.StatusNotFound})
+	if state := CircuitBreakerState("device"); state != CircuitClosed {
+		t.Errorf("expected closed circuit after successful probe, got %s", state)
+	}
+
+	expected := []string{"device CLOSED->OPEN", "device OPEN->HALF_OPEN", "device HALF_OPEN->CLOSED"}
+	if strings.Join(changes, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("unexpected state changes %v", changes)
+	}
+}
+
+func TestCache(t *testing.T) {
+	EnableCache(CacheOptions{})
+	defer DisableCache()
+
+	storeCache("organization/12/locations", []byte(`[{"id":1,"name":"Paris"}]`))
+	storeCache("organizations", []byte(`[{"id":12,"name":"Acme"}]`))
+	storeCache("devices", []byte(`[]`))
+
+	locations, err := ListOrganizationLocations(12)
+	if err != nil || len(locations) != 1 || locations[0].Name != "Paris" {
+		t.Fatalf("expected cached locations, got %+v (%v)", locations, err)
+	}
+
+	if _, ok := cache.Cache.Get("devices"); ok {
+		t.Error("devices must not be cached")
+	}
+
+	invalidateCacheFor("organization/12/locations/1")
+
+	for _, key := range []string{"organization/12/locations", "organizations"} {
+		if _, ok := cache.Cache.Get(key); ok {
+			t.Errorf("%s must be invalidated", key)
+		}
+	}
+
+	memory := NewMemoryCache(2)
+	memory.Set("a", []byte("a"), time.Minute)
+	memory.Set("b", []byte("b"), time.Minute)
+	memory.Get("a")
+	memory.Set("c", []byte("c"), time.Minute)
+	if _, ok := memory.Get("b"); ok {
+		t.Error("least recently used entry must be evicted")
+	}
+	memory.Set("d", []byte("d"), -time.Second)
+	if _, ok := memory.Get("d"); ok {
+		t.Error("expired entry must not be returned")
+	}
+}
+
+func TestResolveByName(t *testing.T) {
+	names := []string{"Acme Corp", "ACME Corp", "Paris office", "Lyon office", "Windows Server"}
+	identity := func(name string) string { return name }
+
+	cases := []struct {
+		query string
+		want  string
+		err   error
+	}{
+		{"Acme Corp", "Acme Corp", nil},
+		{"acme corp", "", ErrAmbiguousName},
+		{"paris-office", "Paris office", nil},
+		{"Windows Servre", "Windows Server", nil},
+		{"office", "", ErrAmbiguousName},
+		{"Berlin", "", ErrNameNotFound},
+	}
+
+	for _, c := range cases {
+		got, err := resolveByName("test", c.query, names, identity)
+		if c.err != nil {
+			if !errors.Is(err, c.err) {
+				t.Errorf("%q: expected %v, got %v", c.query, c.err, err)
+			}
+		} else if err != nil || got != c.want {
+			t.Errorf("%q: expected %q, got %q (%v)", c.query, c.want, got, err)
+		}
+	}
+}
+
+func TestDeviceReferences(t *testing.T) {
+	calls := 0
+	roles := newIdentityMap("role", func(ctx context.Context) ([]DeviceRole, error) {
+		calls++
+		return []DeviceRole{{ID: 1, Name: "Server"}, {ID: 2, Name: "Workstation"}}, nil
+	}, func(r DeviceRole) int { return r.ID })
+
+	original := rolesByID
+	rolesByID = roles
+	defer func() { rolesByID = original }()
+
+	ctx := context.Background()
+	for _, id := range []int{1, 2, 1} {
+		role, err := Device{NodeRoleID: id}.Role(ctx)
+		if err != nil || role.ID != id {
+			t.Fatalf("unexpected role %+v (%v)", role, err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected roles to be listed once, got %d calls", calls)
+	}
+
+	device := Device{NodeRoleID: 3}
+	device.References.Role = DeviceRole{ID: 3, Name: "Referenced"}
+	if role, err := device.Role(ctx); err != nil || role.Name != "Referenced" || calls != 1 {
+		t.Errorf("expected role from references, got %+v (%v)", role, err)
+	}
+
+	// Missing IDs are remembered after a reload
+	for i := 0; i < 2; i++ {
+		if _, err := (Device{NodeRoleID: 4}).Role(ctx); err == nil {
+			t.Error("expected unknown role error")
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("expected one reload for a missing role, got %d calls", calls)
+	}
+
+	// Mutations of roles clear the map
+	invalidateReferencesFor("role/2")
+	if _, err := (Device{NodeRoleID: 1}).Role(ctx); err != nil || calls != 3 {
+		t.Errorf("expected roles to be listed again after a change, got %d calls (%v)", calls, err)
+	}
+}
+
+func TestPolicyTree(t *testing.T) {
+	policies := []Policy{
+		{ID: 1, Name: "Base"},
+		{ID: 2, Name: "Servers", ParentPolicyID: 1},
+		{ID: 3, Name: "SQL Servers", ParentPolicyID: 2},
+		{ID: 4, Name: "Workstations", ParentPolicyID: 1},
+	}
+
+	tree, err := BuildPolicyTree(policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ancestors := tree.Ancestors(3); len(ancestors) != 2 || ancestors[0].ID != 2 || ancestors[1].ID != 1 {
+		t.Errorf("unexpected ancestors: %+v", ancestors)
+	}
+
+	if descendants := tree.Descendants(1); len(descendants) != 3 {
+		t.Errorf("unexpected descendants: %+v", descendants)
+	}
+
+	device := Device{ID: 7, NodeRoleID: 5, RolePolicyID: 2, PolicyID: 3}
+	organization := OrganizationDetailed{ID: 12, Name: "Acme", Policies: []OrganizationPolicyItem{{NodeRoleID: 5, PolicyID: 2}}}
+
+	var effective EffectivePolicy
+	effective.explain(device, DeviceRole{ID: 5, Name: "Windows Server"}, organization, tree)
+	if effective.Source != PolicySourceDevice || effective.Policy.ID != 3 || effective.MappedPolicyID != 2 || len(effective.Ancestors) != 2 {
+		t.Errorf("unexpected effective policy:\n%s", effective)
+	}
+
+	policies[0].ParentPolicyID = 3
+	if _, err := BuildPolicyTree(policies); !errors.Is(err, ErrPolicyCycle) {
+		t.Errorf("expected policy cycle, got %v", err)
+	}
+}
+
+func TestPolicyDrift(t *testing.T) {
+	reference := []OrganizationPolicyItem{{NodeRoleID: 1, PolicyID: 10}, {NodeRoleID: 2, PolicyID: 20}}
+	organizations := []OrganizationDetailed{
+		{ID: 2, Name: "Drifted", Policies: []OrganizationPolicyItem{{NodeRoleID: 1, PolicyID: 11}, {NodeRoleID: 3, PolicyID: 30}}},
+		{ID: 1, Name: "Aligned", Policies: reference},
+	}
+
+	report := DetectPolicyDrift(reference, organizations)
+
+	drifted := report.Drifted()
+	if len(drifted) != 1 || drifted[0].OrganizationID != 2 {
+		t.Fatalf("unexpected drifted organizations: %+v", drifted)
+	}
+
+	drift := drifted[0]
+	if len(drift.Missing) != 1 || drift.Missing[0].NodeRoleID != 2 ||
+		len(drift.Extra) != 1 || drift.Extra[0].NodeRoleID != 3 ||
+		len(drift.Mismatched) != 1 || drift.Mismatched[0].ActualPolicyID != 11 {
+		t.Errorf("unexpected drift: %+v", drift)
+	}
+
+	results := report.Remediate(PolicyRemediationOptions{DryRun: true})
+	if len(results) != 1 || len(results[0].Policies) != 3 || results[0].Policies[0].PolicyID != 10 {
+		t.Errorf("unexpected remediation: %+v", results)
+	}
+}
+
+func TestOrganizationsPlan(t *testing.T) {
+	config, err := ReadOrganizationsConfig(strings.NewReader(`{"organizations": [
+		{"name": "Acme", "description": "Acme Corp", "customFields": {"contract": "AC-42"}, "locations": [{"name": "Paris"}]},
+		{"name": "Globex", "description": "Globex", "customFields": {"contract": "GL-1"}, "policies": [{"nodeRoleId": 1, "policyId": 12}],
+			"locations": [{"name": "Lyon", "address": "2 rue"}, {"name": "Nice"}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := []OrganizationDetailed{{
+		ID:          7,
+		Name:        "Globex",
+		Description: "Globex",
+		Fields:      CustomFields{"contract": "GL-1", "other": "kept"},
+		Policies:    []OrganizationPolicyItem{{NodeRoleID: 1, PolicyID: 11}, {NodeRoleID: 2, PolicyID: 20}},
+		Locations:   []Location{{ID: 3, Name: "Lyon", Address: "1 rue"}, {ID: 4, Name: "Nice"}},
+	}}
+
+	yamlConfig, err := ReadOrganizationsConfig(strings.NewReader(`
+organizations:
+  - name: Acme
+    description: Acme Corp
+    customFields:
+      contract: AC-42
+    locations:
+      - name: Paris
+  - name: Globex
+    description: Globex
+    customFields:
+      contract: GL-1
+    policies:
+      - nodeRoleId: 1
+        policyId: 12
+    locations:
+      - name: Lyon
+        address: 2 rue
+      - name: Nice
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buildOrganizationsPlan(yamlConfig, live).String() != buildOrganizationsPlan(config, live).String() {
+		t.Errorf("YAML and JSON configurations must give the same plan:\n%+v", yamlConfig)
+	}
+
+	if _, err := ReadOrganizationsConfig(strings.NewReader("organizations:\n  - name: Acme\n    unknown: true\n")); err == nil {
+		t.Error("expected unknown field error")
+	}
+
+	plan := buildOrganizationsPlan(config, live)
+
+	expected := `+ create organization 'Acme'
+    description: "" -> "Acme Corp"
+~ update custom fields of organization 'Acme'
+    contract: null -> "AC-42"
++ create location 'Paris' of organization 'Acme'
+~ update location 'Lyon' (3) of organization 'Globex' (7)
+    address: "1 rue" -> "2 rue"
+~ update policy mappings of organization 'Globex' (7)
+    role 1: 11 -> 12
+Plan: 2 to create, 3 to update, 6 unchanged.`
+	if plan.String() != expected {
+		t.Errorf("unexpected plan:\n%s", plan)
+	}
+
+	var intercepted []DryRunRequest
+	EnableDryRun(func(request DryRunRequest) {
+		intercepted = append(intercepted, request)
+	})
+	defer DisableDryRun()
+
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests depending on the created organization target its placeholder ID
+	expectedRequests := []string{
+		`POST organizations {"name":"Acme","description":"Acme Corp"}`,
+		`PATCH organization/-1/custom-fields {"contract":"AC-42"}`,
+		`POST organization/-1/locations {"name":"Paris"}`,
+		`PATCH organization/7/locations/3 {"address":"2 rue"}`,
+		`PUT organization/7/policies [{"nodeRoleId":1,"policyId":12},{"nodeRoleId":2,"policyId":20}]`,
+	}
+	if len(intercepted) != len(expectedRequests) {
+		t.Fatalf("unexpected intercepted requests: %+v", intercepted)
+	}
+	for i, request := range intercepted {
+		if got := request.Method + " " + request.Path + " " + string(request.Payload); got != expectedRequests[i] {
+			t.Errorf("request %d: got %s, want %s", i, got, expectedRequests[i])
+		}
+	}
+}
+
+func TestSnapshotArchive(t *testing.T) {
+	archive := SnapshotArchive{
+		Version: SnapshotVersion,
+		Organizations: []OrganizationSnapshot{{
+			Organization: OrganizationDetailed{ID: 1, Name: "Acme", Policies: []OrganizationPolicyItem{{NodeRoleID: 1, PolicyID: 2}}},
+			CustomFields: CustomFields{"contract": "AC-42"},
+			Locations:    []LocationSnapshot{{Location: Location{ID: 3, Name: "Paris"}}},
+		}},
+	}
+
+	var buffer strings.Builder
+	if err := archive.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadSnapshotArchive(strings.NewReader(buffer.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read.Organizations) != 1 || read.Organizations[0].Locations[0].Location.Name != "Paris" || read.Organizations[0].CustomFields.StringField("contract") != "AC-42" {
+		t.Errorf("unexpected archive: %+v", read)
+	}
+
+	if _, err := ReadSnapshotArchive(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Error("expected unsupported version error")
+	}
+
+	// Policy 2 is mapped explicitly, role 1 by name
+	archive.Roles = map[int]string{1: "Server"}
+	archive.Organizations[0].Organization.Policies = append(archive.Organizations[0].Organization.Policies, OrganizationPolicyItem{NodeRoleID: 4, PolicyID: 5})
+
+	roles := []DeviceRole{{ID: 11, Name: "Server"}, {ID: 14, Name: "Workstation"}}
+	options, err := archive.resolveIDs(SnapshotImportOptions{PolicyIDs: map[int]int{2: 20}}, roles, nil)
+	if err == nil || err.Error() != "unmapped role IDs [4] and policy IDs [5], map them with SnapshotImportOptions" {
+		t.Errorf("expected unmapped IDs error, got %v", err)
+	}
+
+	archive.Policies = map[int]string{5: "Workstations"}
+	options, err = archive.resolveIDs(SnapshotImportOptions{RoleIDs: map[int]int{4: 14}, PolicyIDs: map[int]int{2: 20}}, roles, []Policy{{ID: 15, Name: "Workstations"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.role(1) != 11 || options.role(4) != 14 || options.policy(2) != 20 || options.policy(5) != 15 {
+		t.Errorf("unexpected ID remapping %+v", options)
+	}
+}
+
+func TestOrganizationSettings(t *testing.T) {
+	organization := OrganizationDetailed{Settings: map[string]OrganizationSetting{
+		SettingTrayIcon: {Product: SettingTrayIcon, Enabled: true, Options: map[string]any{"supportEmail": "old@example.com", "unknown": 42.0}},
+	}}
+
+	trayIcon, err := organization.TrayIconOptions()
+	if err != nil || trayIcon.SupportEmail != "old@example.com" {
+		t.Fatalf("unexpected tray icon options %+v: %v", trayIcon, err)
+	}
+
+	trayIcon.SupportEmail = "support@example.com"
+	if err := organization.SetTrayIconOptions(trayIcon); err != nil {
+		t.Fatal(err)
+	}
+
+	options := organization.Settings[SettingTrayIcon].Options
+	if options["supportEmail"] != "support@example.com" || options["unknown"] != 42.0 || !organization.Settings[SettingTrayIcon].Enabled {
+		t.Errorf("unexpected tray icon setting: %+v", organization.Settings[SettingTrayIcon])
+	}
+
+	if err := organization.SetBackupOptions(BackupOptions{CloudStorage: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if backup := organization.Settings[SettingBackup]; backup.Product != SettingBackup || backup.Options["cloudStorage"] != true {
+		t.Errorf("unexpected backup setting: %+v", backup)
+	}
+}
+
+func TestUserUpdateJSON(t *testing.T) {
+	update := UserUpdate{}
+	if !update.IsEmpty() {
+		t.Error("expected empty update")
+	}
+
+	update.SetEnabled(false).SetDeviceIDs(nil)
+
+	data, err := json.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"enabled":false,"deviceIds":[]}` {
+		t.Errorf("unexpected user update JSON: %s", data)
+	}
+
+	// Same keys as User
+	data, err = json.Marshal(new(UserUpdate).SetFirstname("Jane").SetLastname("Doe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"firstname":"Jane","lastname":"Doe"}` {
+		t.Errorf("unexpected user update JSON: %s", data)
+	}
+
+	if UpdateEndUser(0, update) == nil || UpdateTechnician(0, update) == nil || DeleteEndUser(0) == nil {
+		t.Error("expected error without user ID")
+	}
+
+	if err := ResendInvitation(User{ID: 1, InvitationStatus: InvitationStatusRegistered}); err == nil {
+		t.Error("expected error resending invitation to a registered user")
+	}
+}
+
+func TestUserSecurityReport(t *testing.T) {
+	report := BuildUserSecurityReport([]User{
+		{ID: 1, Email: "admin@example.com", Enabled: true, UserType: UserTypeTechnician, Administrator: true, MFAConfigured: true},
+		{ID: 2, Email: "tech@example.com", Enabled: true, UserType: UserTypeTechnician, InvitationStatus: InvitationStatusExpired},
+		{ID: 3, Email: "user@example.com", UserType: UserTypeEndUser, OrganizationID: 5, MFAConfigured: true, DeviceIDs: []int{7}},
+		{ID: 4, Email: "former@example.com", UserType: UserTypeEndUser, OrganizationID: 5},
+		{ID: 5, Email: "new@example.com", Enabled: true, UserType: UserTypeTechnician, InvitationStatus: InvitationStatusExpired},
+	})
+
+	if len(report.Organizations) != 2 || report.Organizations[0].OrganizationID != 0 || report.Organizations[1].OrganizationID != 5 {
+		t.Fatalf("unexpected organizations: %+v", report.Organizations)
+	}
+
+	technicians := report.Organizations[0]
+	// Same score, sorted by user ID
+	if technicians.Users[0].User.ID != 2 || technicians.Users[1].User.ID != 5 || technicians.Users[0].Score != 40 || technicians.Score != (40+40+90)/3 {
+		t.Errorf("unexpected technicians: %+v", technicians)
+	}
+
+	// Disabled users without MFA are flagged
+	if endUsers := report.Organizations[1]; endUsers.Users[0].User.ID != 4 || endUsers.Users[0].Score != 60 {
+		t.Errorf("unexpected end users: %+v", endUsers)
+	}
+
+	if report.Score != (90+40+80+60+40)/5 || report.Findings[UserFindingDisabledWithDevices] != 1 || report.Findings[UserFindingNoMFA] != 3 || len(report.Flagged()) != 5 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	var buffer strings.Builder
+	if err := report.WriteCSV(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buffer.String(), "0,2,tech@example.com,TECHNICIAN,40,NO_MFA|INVITATION_EXPIRED\n") {
+		t.Errorf("unexpected CSV:\n%s", buffer.String())
+	}
+}
+
+func TestContactSync(t *testing.T) {
+	csvEntries, err := ReadDirectoryCSV(strings.NewReader("Email,First Name,Last Name,Department\njohn@example.com,John,Doe,IT\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ldifEntries, err := ReadDirectoryLDIF(strings.NewReader(`version: 1
+
+dn: ou=people,dc=example,dc=com
+objectClass: organizationalUnit
+
+dn: cn=Jane Roe,ou=people,dc=example,dc=com
+mail: jane@example.com
+givenName:: SmFuZQ==
+sn: Roe
+title: Account
+  Manager
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(csvEntries) != 1 || csvEntries[0].FirstName != "John" || len(ldifEntries) != 1 || ldifEntries[0].FirstName != "Jane" || ldifEntries[0].JobTitle != "Account Manager" {
+		t.Fatalf("unexpected entries: %+v %+v", csvEntries, ldifEntries)
+	}
+
+	contacts := []Contact{
+		{ID: 1, ClientID: 5, Email: "John@example.com", FirstName: "John", LastName: "Smith"},
+		{ID: 2, ClientID: 5, Email: "old@example.com", JobTitle: "Buyer"},
+		{ID: 3, ClientID: 5, Email: "gone@example.com", JobTitle: "[Inactive]"},
+		{ID: 4, ClientID: 5, Email: "jane@example.com", FirstName: "Jane", LastName: "Roe", JobTitle: "[Inactive] Account Manager"},
+	}
+
+	plan, err := buildContactSyncPlan(5, contacts, append(csvEntries, ldifEntries...), ContactSyncOptions{DeactivateMissing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deactivated contacts back in the directory are reactivated, already
+	// deactivated ones are left as they are
+	expected := `~ update contact 'jane@example.com' (4)
+    jobTitle: "[Inactive] Account Manager" -> "Account Manager"
+~ update contact 'John@example.com' (1)
+    lastName: "Smith" -> "Doe"
+- deactivate contact 'old@example.com' (2)
+    jobTitle: "Buyer" -> "[Inactive] Buyer"
+Plan: 0 to create, 2 to update, 1 to deactivate, 1 unchanged.`
+	if plan.String() != expected {
+		t.Errorf("unexpected plan:\n%s", plan)
+	}
+
+	plan, err = buildContactSyncPlan(5, contacts[:1], ldifEntries, ContactSyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `+ create contact 'jane@example.com'
+    firstName: "" -> "Jane"
+    lastName: "" -> "Roe"
+    jobTitle: "" -> "Account Manager"
+Plan: 1 to create, 0 to update, 0 to deactivate, 1 unchanged.`
+	if plan.String() != expected {
+		t.Errorf("unexpected plan:\n%s", plan)
+	}
+}
+
+func TestTicketMetadata(t *testing.T) {
+	metadata := TicketMetadata{
+		Forms:    []TicketForm{{ID: 1, Name: "Default", Fields: []TicketFormField{{ID: 10, Required: true}}}},
+		Statuses: []TicketStatus{{Name: "NEW", DisplayName: "New", StatusID: 1000}},
+		Attributes: []TicketAttributeDefinition{{
+			ID:            10,
+			Name:          "Billing code",
+			AttributeType: CustomFieldTypeDropdown,
+			Content:       CustomFieldContent{Values: []CustomFieldOption{{ID: "aaaa-bbbb", Name: "Internal"}}},
+		}},
+	}
+
+	ticket := Ticket{}
+	if err := metadata.setAttribute(&ticket, "billing code", "Internal"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ticket.AttributeValues) != 1 || ticket.AttributeValues[0].Value != "aaaa-bbbb" {
+		t.Errorf("unexpected attribute values: %+v", ticket.AttributeValues)
+	}
+
+	if value, err := metadata.attribute(ticket, "Billing code"); err != nil || value != "Internal" {
+		t.Errorf("unexpected attribute value %v: %v", value, err)
+	}
+
+	newTicket := NewTicket{ClientID: 1, TicketFormID: 1, Subject: "Printer", Status: "New"}
+	if err := metadata.Validate(newTicket); err == nil || !strings.Contains(err.Error(), "'Billing code' required") {
+		t.Errorf("expected required attribute error, got %v", err)
+	}
+
+	newTicket.Attributes = []TicketAttributes{{AttributeID: 10, Value: "aaaa-bbbb"}}
+	if err := metadata.Validate(newTicket); err != nil {
+		t.Error(err)
+	}
+
+	newTicket.Status = "CLOSED"
+	if err := metadata.Validate(newTicket); err == nil {
+		t.Error("expected unknown status error")
+	}
+}
+
+func TestBoardQuery(t *testing.T) {
+	from := time.Unix(1700000000, 0)
+	query := NewBoardQuery().
+		StatusIn("NEW", "OPEN").
+		PriorityIn(PriorityHigh).
+		Unassigned().
+		CreatedBetween(from, from.Add(time.Hour)).
+		SortBy(BoardFieldCreateTime, SortDescending).
+		PageSize(50)
+
+	options, err := query.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"sortBy":[{"field":"createTime","direction":"DESC"}],"filters":[` +
+		`{"field":"status","operator":"in","value":"NEW,OPEN"},` +
+		`{"field":"priority","operator":"in","value":"HIGH"},` +
+		`{"field":"assignedAppUser","operator":"not_present","value":""},` +
+		`{"field":"createTime","operator":"between","value":"1700000000,1700003600"}],"pageSize":50}`
+	if string(data) != expected {
+		t.Errorf("unexpected options JSON: %s", data)
+	}
+
+	if err := query.Validate("status", "priority", "createTime"); err == nil || !strings.Contains(err.Error(), "'assignedAppUser' is not a board column") {
+		t.Errorf("expected column error, got %v", err)
+	}
+
+	if _, err := NewBoardQuery().Where(BoardFieldCreateTime, BoardOperatorContains, "x").Options(); err == nil {
+		t.Error("expected operator error")
+	}
+
+	// ninjarmm.Time values are accepted for dates, as from ticket fields
+	options, err = NewBoardQuery().Where(BoardFieldLastUpdated, BoardOperatorGreaterThan, Time(from)).Options()
+	if err != nil || options.Filters[0].Value != "1700000000" {
+		t.Errorf("unexpected ninjarmm.Time filter %+v (%v)", options.Filters, err)
+	}
+
+	if _, err := NewBoardQuery().Where(BoardFieldLastUpdated, BoardOperatorGreaterThan, "yesterday").Options(); err == nil {
+		t.Error("expected date value error")
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestUpdateOrganizationDocument(t *testing.T) {
+	var intercepted []DryRunRequest
+	EnableDryRun(func(request DryRunRequest) {
+		intercepted = append(intercepted, request)
+	})
+	defer DisableDryRun()
+
+	if err := UpdateOrganizationDocument(1, Document{ClientDocumentName: "Network"}); err == nil {
+		t.Error("expected error without document ID")
+	}
+
+	if err := UpdateOrganizationDocument(1, Document{ClientDocumentID: 4, ClientDocumentName: "Network"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(intercepted) != 1 || intercepted[0].Path != "organization/1/document/4" || !strings.Contains(string(intercepted[0].Payload), `"clientDocumentName":"Network"`) {
+		t.Errorf("unexpected intercepted requests: %+v", intercepted)
+	}
+}
+
+func TestUpdateTicketPartial(t *testing.T) {
+	original := Ticket{ID: 3, Version: 7, Subject: "Printer", Tags: []string{"hardware"}, ClientID: 1, TicketFormID: 2, Status: TicketStatus{Name: "OPEN", DisplayName: "Open", StatusID: 2000}}
+	modified := original
+	modified.Subject = "Printer jammed"
+	modified.Tags = nil
+
+	var intercepted []DryRunRequest
+	EnableDryRun(func(request DryRunRequest) {
+		intercepted = append(intercepted, request)
+	})
+	defer DisableDryRun()
+
+	if _, err := UpdateTicketPartial(context.Background(), original, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	// Required fields are always sent, cleared lists as empty lists
+	if len(intercepted) != 1 || intercepted[0].Method != "PUT" || string(intercepted[0].Payload) != `{"clientId":1,"status":"2000","subject":"Printer jammed","tags":[],"ticketFormId":2,"version":7}` {
+		t.Errorf("unexpected intercepted requests: %+v", intercepted)
+	}
+
+	// Status and CC list in the write API format
+	reassigned := original
+	reassigned.Status = TicketStatus{StatusID: 3000}
+	reassigned.CcList.Emails = []string{"boss@example.com"}
+	if changes := ticketChanges(original, reassigned); len(changes) != 2 || changes["status"] != "3000" || changes["cc"] == nil {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	// Zero values are changes
+	assigned := original
+	assigned.AssignedAppUserID = 12
+	unassigned := assigned
+	unassigned.AssignedAppUserID = 0
+	if changes := ticketChanges(assigned, unassigned); len(changes) != 1 || changes["assignedAppUserId"] != 0 {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	if changes := ticketChanges(original, original); len(changes) != 0 {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	DisableDryRun()
+
+	// The API enforces the version with 409 Conflict
+	originalAuth, originalClient := auth, client
+	defer func() { auth, client = originalAuth, originalClient }()
+
+	var sent map[string]any
+	auth = &authResponse{AccessToken: "test", expiresAt: time.Now().Add(time.Hour)}
+	client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		json.NewDecoder(req.Body).Decode(&sent)
+		return &http.Response{StatusCode: http.StatusConflict, Body: io.NopCloser(strings.NewReader(`{"error":"version mismatch"}`)), Header: http.Header{}}, nil
+	})}
+
+	_, err := UpdateTicketPartial(context.Background(), original, modified)
+	var conflictError *TicketConflictError
+	if !errors.As(err, &conflictError) || conflictError.Version != 7 || strings.Join(conflictError.Fields, ",") != "subject,tags" || sent["version"] != float64(7) {
+		t.Errorf("expected conflict on version 7, got %v (sent %v)", err, sent)
+	}
+
+	conflict := &TicketConflictError{TicketID: 3, Version: 7, Err: &APIError{StatusCode: http.StatusConflict}}
+	var apiError *APIError
+	if !errors.Is(conflict, ErrTicketConflict) || !errors.As(conflict, &apiError) {
+		t.Error("expected conflict error to match ErrTicketConflict and wrap the API error")
+	}
+}
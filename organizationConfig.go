@@ -0,0 +1,521 @@
+package ninjarmm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Read a declarative organizations configuration from YAML or JSON (JSON
+// documents are valid YAML). Field names are the same in both formats,
+// unknown fields are rejected.
+//
+// Example:
+//
+//	organizations:
+//	  - name: Acme
+//	    description: Acme Corp
+//	    nodeApprovalMode: AUTOMATIC
+//	    customFields:
+//	      contractId: AC-42
+//	    policies:
+//	      - nodeRoleId: 1
+//	        policyId: 12
+//	    locations:
+//	      - name: Paris
+//	        address: 1 rue de Rivoli
+func ReadOrganizationsConfig(r io.Reader) (config OrganizationsConfig, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		err = fmt.Errorf("error reading organizations configuration: %w", err)
+		return
+	}
+
+	// YAML is converted to JSON to share the JSON field names and checks
+	var document interface{}
+	if err = yaml.Unmarshal(data, &document); err != nil {
+		err = fmt.Errorf("error decoding organizations configuration: %w", err)
+		return
+	}
+
+	data, err = json.Marshal(document)
+	if err != nil {
+		err = fmt.Errorf("error decoding organizations configuration: %w", err)
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&config)
+	if err != nil {
+		err = fmt.Errorf("error decoding organizations configuration: %w", err)
+		return
+	}
+
+	err = config.validate()
+	return
+}
+
+// Check names are set and unique, they identify organizations and locations
+func (config OrganizationsConfig) validate() error {
+	organizations := make(map[string]bool)
+
+	for _, organization := range config.Organizations {
+		if organization.Name == "" {
+			return fmt.Errorf("organization name required")
+		}
+		if organizations[organization.Name] {
+			return fmt.Errorf("organization '%s' declared twice", organization.Name)
+		}
+		organizations[organization.Name] = true
+
+		locations := make(map[string]bool)
+		for _, location := range organization.Locations {
+			if location.Name == "" {
+				return fmt.Errorf("location name required in organization '%s'", organization.Name)
+			}
+			if locations[location.Name] {
+				return fmt.Errorf("location '%s' declared twice in organization '%s'", location.Name, organization.Name)
+			}
+			locations[location.Name] = true
+		}
+	}
+
+	return nil
+}
+
+// Compute the changes needed to make the live organizations match the
+// configuration.
+//
+// Organizations and locations are matched by name. Only the fields set in
+// the configuration are managed: empty fields, custom fields and policy
+// mappings that are not declared are left untouched.
+func PlanOrganizations(config OrganizationsConfig) (plan OrganizationsPlan, err error) {
+	err = config.validate()
+	if err != nil {
+		return
+	}
+
+	organizations, err := ListOrganizationsDetailed()
+	if err != nil {
+		err = fmt.Errorf("error listing organizations: %w", err)
+		return
+	}
+
+	declared := make(map[string]bool, len(config.Organizations))
+	for _, organization := range config.Organizations {
+		declared[organization.Name] = true
+	}
+
+	// Only fetch the live state of the declared organizations
+	var live []OrganizationDetailed
+	for _, organization := range organizations {
+		if !declared[organization.Name] {
+			continue
+		}
+
+		organization.Fields, err = GetOrganizationCustomFields(organization.ID)
+		if err != nil {
+			err = fmt.Errorf("error getting organization %d custom fields: %w", organization.ID, err)
+			return
+		}
+
+		organization.Locations, err = ListOrganizationLocations(organization.ID)
+		if err != nil {
+			err = fmt.Errorf("error listing organization %d locations: %w", organization.ID, err)
+			return
+		}
+
+		for i, location := range organization.Locations {
+			organization.Locations[i].Fields, err = GetLocationCustomFields(organization.ID, location.ID)
+			if err != nil {
+				err = fmt.Errorf("error getting location %d custom fields: %w", location.ID, err)
+				return
+			}
+		}
+
+		live = append(live, organization)
+	}
+
+	return buildOrganizationsPlan(config, live), nil
+}
+
+// Compare the configuration against the live organizations, whose Fields
+// and Locations (with their Fields) must be populated
+func buildOrganizationsPlan(config OrganizationsConfig, live []OrganizationDetailed) (plan OrganizationsPlan) {
+	byName := make(map[string]OrganizationDetailed, len(live))
+	for _, organization := range live {
+		byName[organization.Name] = organization
+	}
+
+	for _, declared := range config.Organizations {
+		current, exists := byName[declared.Name]
+
+		change := PlanChange{
+			Resource:         PlanResourceOrganization,
+			OrganizationID:   current.ID,
+			OrganizationName: declared.Name,
+			organization:     declared,
+		}
+
+		if !exists {
+			change.Action = PlanActionCreate
+			change.Diffs = appendPlanDiff(change.Diffs, "description", "", declared.Description)
+			change.Diffs = appendPlanDiff(change.Diffs, "nodeApprovalMode", "", declared.NodeApprovalMode)
+		} else {
+			change.Diffs = appendPlanDiff(change.Diffs, "description", current.Description, declared.Description)
+			change.Diffs = appendPlanDiff(change.Diffs, "nodeApprovalMode", current.NodeApprovalMode, declared.NodeApprovalMode)
+			change.Action = planAction(len(change.Diffs) > 0)
+		}
+		plan.Changes = append(plan.Changes, change)
+
+		plan.Changes = append(plan.Changes, PlanChange{
+			Resource:         PlanResourceOrganizationCustomFields,
+			OrganizationID:   current.ID,
+			OrganizationName: declared.Name,
+			Diffs:            customFieldsPlanDiffs(current.Fields, declared.CustomFields),
+		}.withAction())
+
+		locations := make(map[string]Location, len(current.Locations))
+		for _, location := range current.Locations {
+			locations[location.Name] = location
+		}
+
+		for _, declaredLocation := range declared.Locations {
+			currentLocation, locationExists := locations[declaredLocation.Name]
+
+			change := PlanChange{
+				Resource:         PlanResourceLocation,
+				OrganizationID:   current.ID,
+				OrganizationName: declared.Name,
+				LocationID:       currentLocation.ID,
+				LocationName:     declaredLocation.Name,
+				location:         declaredLocation,
+			}
+			change.Diffs = appendPlanDiff(change.Diffs, "address", currentLocation.Address, declaredLocation.Address)
+			change.Diffs = appendPlanDiff(change.Diffs, "description", currentLocation.Description, declaredLocation.Description)
+
+			if locationExists {
+				change.Action = planAction(len(change.Diffs) > 0)
+			} else {
+				change.Action = PlanActionCreate
+			}
+			plan.Changes = append(plan.Changes, change)
+
+			plan.Changes = append(plan.Changes, PlanChange{
+				Resource:         PlanResourceLocationCustomFields,
+				OrganizationID:   current.ID,
+				OrganizationName: declared.Name,
+				LocationID:       currentLocation.ID,
+				LocationName:     declaredLocation.Name,
+				Diffs:            customFieldsPlanDiffs(currentLocation.Fields, declaredLocation.CustomFields),
+			}.withAction())
+		}
+
+		if len(declared.Policies) > 0 {
+			drift := DetectPolicyDrift(declared.Policies, []OrganizationDetailed{current}).Organizations[0]
+
+			change := PlanChange{
+				Resource:         PlanResourcePolicyMappings,
+				OrganizationID:   current.ID,
+				OrganizationName: declared.Name,
+				policies:         drift.desiredPolicies(declared.Policies, false),
+			}
+			for _, missing := range drift.Missing {
+				change.Diffs = append(change.Diffs, PlanDiff{Field: fmt.Sprintf("role %d", missing.NodeRoleID), New: missing.PolicyID})
+			}
+			for _, mismatch := range drift.Mismatched {
+				change.Diffs = append(change.Diffs, PlanDiff{Field: fmt.Sprintf("role %d", mismatch.NodeRoleID), Old: mismatch.ActualPolicyID, New: mismatch.ExpectedPolicyID})
+			}
+			plan.Changes = append(plan.Changes, change.withAction())
+		}
+	}
+
+	return
+}
+
+// Custom fields declared with a value different from the live one
+func customFieldsPlanDiffs(current, declared CustomFields) (diffs []PlanDiff) {
+	for key, value := range declared {
+		if !customFieldEqual(current[key], value) {
+			diffs = append(diffs, PlanDiff{Field: key, Old: current[key], New: value})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Field < diffs[j].Field
+	})
+	return
+}
+
+// Record a field change, empty declared values are not managed
+func appendPlanDiff[T comparable](diffs []PlanDiff, field string, current, declared T) []PlanDiff {
+	var zero T
+	if declared == zero || declared == current {
+		return diffs
+	}
+	return append(diffs, PlanDiff{Field: field, Old: current, New: declared})
+}
+
+func planAction(changed bool) PlanAction {
+	if changed {
+		return PlanActionUpdate
+	}
+	return PlanActionNoOp
+}
+
+// Set the action of custom fields and policy mappings changes, they are
+// always updates of the organization or location
+func (change PlanChange) withAction() PlanChange {
+	change.Action = planAction(len(change.Diffs) > 0)
+	return change
+}
+
+// HasChanges returns true if applying the plan changes something.
+func (plan OrganizationsPlan) HasChanges() bool {
+	for _, change := range plan.Changes {
+		if change.Action != PlanActionNoOp {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the plan, one line per created (+) or updated (~) resource
+// followed by its field changes and a summary.
+//
+// Example:
+//
+//	~ update location 'Paris' (3) of organization 'Globex' (12)
+//	    address: "1 rue de Rivoli" -> "2 rue de Rivoli"
+//	+ create organization 'Acme'
+//	    description: "" -> "Acme Corp"
+//	Plan: 1 to create, 1 to update, 4 unchanged.
+func (plan OrganizationsPlan) String() string {
+	var builder strings.Builder
+	counts := make(map[PlanAction]int)
+
+	for _, change := range plan.Changes {
+		counts[change.Action]++
+		if change.Action == PlanActionNoOp {
+			continue
+		}
+
+		builder.WriteString(change.String())
+		builder.WriteString("\n")
+
+		for _, diff := range change.Diffs {
+			fmt.Fprintf(&builder, "    %s: %s -> %s\n", diff.Field, formatPlanValue(diff.Old), formatPlanValue(diff.New))
+		}
+	}
+
+	fmt.Fprintf(&builder, "Plan: %d to create, %d to update, %d unchanged.", counts[PlanActionCreate], counts[PlanActionUpdate], counts[PlanActionNoOp])
+	return builder.String()
+}
+
+func formatPlanValue(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// String describes the change on one line.
+func (change PlanChange) String() string {
+	symbol := map[PlanAction]string{
+		PlanActionCreate: "+",
+		PlanActionUpdate: "~",
+		PlanActionNoOp:   "=",
+	}[change.Action]
+
+	organization := fmt.Sprintf("organization '%s'", change.OrganizationName)
+	if change.OrganizationID != 0 {
+		organization += fmt.Sprintf(" (%d)", change.OrganizationID)
+	}
+
+	location := fmt.Sprintf("location '%s'", change.LocationName)
+	if change.LocationID != 0 {
+		location += fmt.Sprintf(" (%d)", change.LocationID)
+	}
+
+	action := strings.ToLower(strings.ReplaceAll(string(change.Action), "_", "-"))
+
+	switch change.Resource {
+	case PlanResourceOrganization:
+		return fmt.Sprintf("%s %s %s", symbol, action, organization)
+	case PlanResourceOrganizationCustomFields:
+		return fmt.Sprintf("%s %s custom fields of %s", symbol, action, organization)
+	case PlanResourceLocation:
+		return fmt.Sprintf("%s %s %s of %s", symbol, action, location, organization)
+	case PlanResourceLocationCustomFields:
+		return fmt.Sprintf("%s %s custom fields of %s of %s", symbol, action, location, organization)
+	default:
+		return fmt.Sprintf("%s %s policy mappings of %s", symbol, action, organization)
+	}
+}
+
+// Apply the plan in order with CreateOrganization, UpdateOrganization,
+// CreateLocation, UpdateLocation, the custom fields setters and
+// UpdateOrganizationPolicies.
+//
+// Apply stops at the first error, computing a new plan shows what remains to
+// be done.
+func (plan OrganizationsPlan) Apply() (err error) {
+	// IDs of the organizations and locations created by this plan
+	organizationIDs := make(map[string]int)
+	locationIDs := make(map[string]int)
+
+	for _, change := range plan.Changes {
+		if change.Action == PlanActionNoOp {
+			continue
+		}
+
+		organizationID := change.OrganizationID
+		if organizationID == 0 {
+			organizationID = organizationIDs[change.OrganizationName]
+		}
+
+		locationID := change.LocationID
+		if locationID == 0 {
+			locationID = locationIDs[change.OrganizationName+"/"+change.LocationName]
+		}
+
+		switch change.Resource {
+		case PlanResourceOrganization:
+			if change.Action == PlanActionCreate {
+				var created OrganizationDetailed
+				created, err = CreateOrganization(OrganizationDetailed{
+					Name:             change.organization.Name,
+					Description:      change.organization.Description,
+					NodeApprovalMode: change.organization.NodeApprovalMode,
+				}, change.organization.TemplateOrganizationID)
+				organizationIDs[change.OrganizationName] = created.ID
+			} else {
+				err = UpdateOrganization(Organization{
+					ID:               organizationID,
+					Description:      change.organization.Description,
+					NodeApprovalMode: change.organization.NodeApprovalMode,
+				})
+			}
+
+		case PlanResourceLocation:
+			location := Location{
+				Name:        change.location.Name,
+				Address:     change.location.Address,
+				Description: change.location.Description,
+			}
+			if change.Action == PlanActionCreate {
+				var created Location
+				created, err = CreateLocation(organizationID, location)
+				locationIDs[change.OrganizationName+"/"+change.LocationName] = created.ID
+			} else {
+				location.Name = ""
+				err = UpdateLocation(organizationID, locationID, location)
+			}
+
+		case PlanResourceOrganizationCustomFields:
+			err = SetOrganizationCustomFields(organizationID, change.customFields())
+
+		case PlanResourceLocationCustomFields:
+			err = SetLocationCustomFields(organizationID, locationID, change.customFields())
+
+		case PlanResourcePolicyMappings:
+			_, err = UpdateOrganizationPolicies(organizationID, change.policies)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("error applying '%s': %w", strings.TrimSpace(change.String()[1:]), err)
+			return
+		}
+	}
+
+	return
+}
+
+// Custom fields to send for a custom fields change
+func (change PlanChange) customFields() CustomFields {
+	customFields := make(CustomFields, len(change.Diffs))
+	for _, diff := range change.Diffs {
+		customFields[diff.Field] = diff.New
+	}
+	return customFields
+}
+
+// Declarative configuration of organizations
+type OrganizationsConfig struct {
+	Organizations []OrganizationConfig `json:"organizations"`
+}
+
+// Declared state of an organization, identified by its name
+type OrganizationConfig struct {
+	Name                   string                   `json:"name"`
+	Description            string                   `json:"description,omitempty"`
+	NodeApprovalMode       ApprovalMode             `json:"nodeApprovalMode,omitempty"`
+	TemplateOrganizationID int                      `json:"templateOrganizationId,omitempty"` // Only used on creation
+	CustomFields           CustomFields             `json:"customFields,omitempty"`           // A null value clears the field
+	Policies               []OrganizationPolicyItem `json:"policies,omitempty"`               // Mappings of other roles are kept
+	Locations              []LocationConfig         `json:"locations,omitempty"`              // Other locations are kept
+}
+
+// Declared state of a location, identified by its name
+type LocationConfig struct {
+	Name         string       `json:"name"`
+	Address      string       `json:"address,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	CustomFields CustomFields `json:"customFields,omitempty"` // A null value clears the field
+}
+
+// Changes needed to make the live organizations match a configuration, see
+// PlanOrganizations
+type OrganizationsPlan struct {
+	Changes []PlanChange // In apply order
+}
+
+type PlanChange struct {
+	Action           PlanAction
+	Resource         PlanResource
+	OrganizationID   int // 0 if the organization is created by the plan
+	OrganizationName string
+	LocationID       int // 0 if the location is created by the plan
+	LocationName     string
+	Diffs            []PlanDiff
+
+	organization OrganizationConfig
+	location     LocationConfig
+	policies     []OrganizationPolicyItem
+}
+
+// Change of a field (or custom field, or role mapping)
+type PlanDiff struct {
+	Field string
+	Old   interface{}
+	New   interface{}
+}
+
+type PlanAction string
+
+const (
+	PlanActionCreate PlanAction = "CREATE"
+	PlanActionUpdate PlanAction = "UPDATE"
+	PlanActionNoOp   PlanAction = "NO_OP"
+)
+
+type PlanResource string
+
+const (
+	PlanResourceOrganization             PlanResource = "ORGANIZATION"
+	PlanResourceOrganizationCustomFields PlanResource = "ORGANIZATION_CUSTOM_FIELDS"
+	PlanResourceLocation                 PlanResource = "LOCATION"
+	PlanResourceLocationCustomFields     PlanResource = "LOCATION_CUSTOM_FIELDS"
+	PlanResourcePolicyMappings           PlanResource = "POLICY_MAPPINGS"
+)
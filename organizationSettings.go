@@ -0,0 +1,222 @@
+package ninjarmm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Products of the organization settings
+const (
+	SettingTrayIcon   = "trayicon"
+	SettingSplashtop  = "splashtop"
+	SettingTeamViewer = "teamviewer"
+	SettingBackup     = "backup"
+	SettingPSA        = "psa"
+)
+
+// Update the settings of an existing organization. Only the given products
+// are changed.
+//
+// Usage:
+//
+//	organization, _ := ninjarmm.GetOrganization(organizationID)
+//	trayIcon, _ := organization.TrayIconOptions()
+//	trayIcon.SupportEmail = "support@example.com"
+//	organization.SetTrayIconOptions(trayIcon)
+//	err := ninjarmm.UpdateOrganizationSettings(organizationID, organization.Settings)
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateOrganization
+func UpdateOrganizationSettings(organizationID int, settings map[string]OrganizationSetting) (err error) {
+	payload := struct {
+		Settings map[string]OrganizationSetting `json:"settings"`
+	}{settings}
+
+	err = request(http.MethodPatch, fmt.Sprintf("organization/%d", organizationID), payload, nil)
+	return
+}
+
+// Update the settings of the organization. Only the products in Settings
+// are changed.
+func (organization OrganizationDetailed) UpdateSettings() (err error) {
+	return UpdateOrganizationSettings(organization.ID, organization.Settings)
+}
+
+// Decode the options of a product setting into `v`, a pointer to one of the
+// *Options structs (or any struct with JSON tags).
+func (setting OrganizationSetting) DecodeOptions(v interface{}) (err error) {
+	data, err := json.Marshal(setting.Options)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Set the options of a product setting from `v`. Options unknown to `v`
+// are kept.
+func (setting *OrganizationSetting) EncodeOptions(v interface{}) (err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	var options map[string]any
+	err = json.Unmarshal(data, &options)
+	if err != nil {
+		return
+	}
+
+	if setting.Options == nil {
+		setting.Options = make(map[string]any, len(options))
+	}
+
+	for key, value := range options {
+		setting.Options[key] = value
+	}
+	return
+}
+
+// Decode the options of a product, zero options if the product has no
+// setting
+func (organization OrganizationDetailed) settingOptions(product string, v interface{}) error {
+	setting, ok := organization.Settings[product]
+	if !ok {
+		return nil
+	}
+
+	if err := setting.DecodeOptions(v); err != nil {
+		return fmt.Errorf("error decoding %s options: %w", product, err)
+	}
+	return nil
+}
+
+// Set the options of a product, the product setting is created if needed
+func (organization *OrganizationDetailed) setSettingOptions(product string, v interface{}) error {
+	if organization.Settings == nil {
+		organization.Settings = make(map[string]OrganizationSetting)
+	}
+
+	setting, ok := organization.Settings[product]
+	if !ok {
+		setting.Product = product
+	}
+
+	if err := setting.EncodeOptions(v); err != nil {
+		return fmt.Errorf("error encoding %s options: %w", product, err)
+	}
+
+	organization.Settings[product] = setting
+	return nil
+}
+
+// Enable or disable a product for the organization, the product setting is
+// created if needed.
+func (organization *OrganizationDetailed) SetSettingEnabled(product string, enabled bool) {
+	if organization.Settings == nil {
+		organization.Settings = make(map[string]OrganizationSetting)
+	}
+
+	setting, ok := organization.Settings[product]
+	if !ok {
+		setting.Product = product
+	}
+
+	setting.Enabled = enabled
+	organization.Settings[product] = setting
+}
+
+// Returns the tray icon options of the organization.
+func (organization OrganizationDetailed) TrayIconOptions() (options TrayIconOptions, err error) {
+	err = organization.settingOptions(SettingTrayIcon, &options)
+	return
+}
+
+// Set the tray icon options of the organization, other options are kept.
+func (organization *OrganizationDetailed) SetTrayIconOptions(options TrayIconOptions) error {
+	return organization.setSettingOptions(SettingTrayIcon, options)
+}
+
+// Returns the Splashtop options of the organization.
+func (organization OrganizationDetailed) SplashtopOptions() (options SplashtopOptions, err error) {
+	err = organization.settingOptions(SettingSplashtop, &options)
+	return
+}
+
+// Set the Splashtop options of the organization, other options are kept.
+func (organization *OrganizationDetailed) SetSplashtopOptions(options SplashtopOptions) error {
+	return organization.setSettingOptions(SettingSplashtop, options)
+}
+
+// Returns the TeamViewer options of the organization.
+func (organization OrganizationDetailed) TeamViewerOptions() (options TeamViewerOptions, err error) {
+	err = organization.settingOptions(SettingTeamViewer, &options)
+	return
+}
+
+// Set the TeamViewer options of the organization, other options are kept.
+func (organization *OrganizationDetailed) SetTeamViewerOptions(options TeamViewerOptions) error {
+	return organization.setSettingOptions(SettingTeamViewer, options)
+}
+
+// Returns the backup options of the organization.
+func (organization OrganizationDetailed) BackupOptions() (options BackupOptions, err error) {
+	err = organization.settingOptions(SettingBackup, &options)
+	return
+}
+
+// Set the backup options of the organization, other options are kept.
+func (organization *OrganizationDetailed) SetBackupOptions(options BackupOptions) error {
+	return organization.setSettingOptions(SettingBackup, options)
+}
+
+// Returns the PSA options of the organization.
+func (organization OrganizationDetailed) PSAOptions() (options PSAOptions, err error) {
+	err = organization.settingOptions(SettingPSA, &options)
+	return
+}
+
+// Set the PSA options of the organization, other options are kept.
+func (organization *OrganizationDetailed) SetPSAOptions(options PSAOptions) error {
+	return organization.setSettingOptions(SettingPSA, options)
+}
+
+// Options of the 'trayicon' setting
+type TrayIconOptions struct {
+	IconURL           string `json:"iconUrl"`
+	SupportEmail      string `json:"supportEmail"`
+	SupportPhone      string `json:"supportPhone"`
+	SupportURL        string `json:"supportUrl"`
+	AllowUserRequests bool   `json:"allowUserRequests"` // End users can send support requests from the tray icon
+}
+
+// Options of the 'splashtop' setting
+type SplashtopOptions struct {
+	RequestPermission   bool `json:"requestPermission"`   // Ask the end user before connecting
+	DisableInput        bool `json:"disableInput"`        // Lock keyboard and mouse during sessions
+	BlankScreen         bool `json:"blankScreen"`         // Blank the screen during sessions
+	ShowConnectionPopup bool `json:"showConnectionPopup"` // Notify the end user of connections
+}
+
+// Options of the 'teamviewer' setting
+type TeamViewerOptions struct {
+	AccountID         string `json:"accountId"`
+	RequestPermission bool   `json:"requestPermission"` // Ask the end user before connecting
+	PolicyID          string `json:"policyId"`          // TeamViewer policy assigned to the devices
+}
+
+// Options of the 'backup' setting
+type BackupOptions struct {
+	CloudStorage        bool     `json:"cloudStorage"`        // Store backups in the cloud
+	LocalStorage        bool     `json:"localStorage"`        // Store backups on a local or network share
+	CloudStorageLimitGB int      `json:"cloudStorageLimitGB"` // 0 for unlimited
+	RetentionDays       int      `json:"retentionDays"`
+	BandwidthLimitKbps  int      `json:"bandwidthLimitKbps"` // 0 for unlimited
+	ExcludedExtensions  []string `json:"excludedExtensions"`
+}
+
+// Options of the 'psa' setting
+type PSAOptions struct {
+	Provider  string `json:"provider"`  // 'CONNECTWISE', 'AUTOTASK'...
+	CompanyID string `json:"companyId"` // Company of the organization in the PSA
+	BoardID   string `json:"boardId"`   // Board receiving the tickets
+}
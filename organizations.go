@@ -54,6 +54,11 @@ func (organization Organization) Update() (err error) {
 //
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateNodeAttributeValues_1
 func SetOrganizationCustomFields(organizationID int, customFields CustomFields) (err error) {
+	customFields, err = prepareCustomFields(customFields)
+	if err != nil {
+		return
+	}
+
 	err = request(http.MethodPatch, fmt.Sprintf("organization/%d/custom-fields", organizationID), customFields, nil)
 	return
 }
@@ -0,0 +1,285 @@
+package ninjarmm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Returned (wrapped in a *PolicyCycleError) when policies inherit from
+// each other in a loop
+var ErrPolicyCycle = errors.New("policy inheritance cycle")
+
+// Build the policy inheritance tree from all device policies
+//
+// See ListDevicePolicies
+func ListPolicyTree() (tree *PolicyTree, err error) {
+	return listPolicyTree(context.Background())
+}
+
+func listPolicyTree(ctx context.Context) (tree *PolicyTree, err error) {
+	var policies []Policy
+	err = requestContext(ctx, http.MethodGet, "policies", nil, &policies)
+	if err != nil {
+		return
+	}
+
+	return BuildPolicyTree(policies)
+}
+
+// BuildPolicyTree links policies by ParentPolicyID. Policies whose parent is
+// unknown are roots. A *PolicyCycleError is returned if policies inherit
+// from each other in a loop.
+func BuildPolicyTree(policies []Policy) (tree *PolicyTree, err error) {
+	tree = &PolicyTree{nodes: make(map[int]*PolicyNode, len(policies))}
+
+	for _, policy := range policies {
+		tree.nodes[policy.ID] = &PolicyNode{Policy: policy}
+	}
+
+	for _, policy := range policies {
+		node := tree.nodes[policy.ID]
+		parent, ok := tree.nodes[policy.ParentPolicyID]
+		if policy.ParentPolicyID == 0 || !ok {
+			tree.Roots = append(tree.Roots, node)
+			continue
+		}
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+
+	// Walk up from every policy, a cycle never reaches a root
+	for _, policy := range policies {
+		visited := map[int]bool{}
+		var chain []int
+		for node := tree.nodes[policy.ID]; node != nil; node = node.Parent {
+			if visited[node.Policy.ID] {
+				tree, err = nil, &PolicyCycleError{PolicyIDs: chain}
+				return
+			}
+			visited[node.Policy.ID] = true
+			chain = append(chain, node.Policy.ID)
+		}
+	}
+
+	sortPolicyNodes(tree.Roots)
+	for _, node := range tree.nodes {
+		sortPolicyNodes(node.Children)
+	}
+
+	return
+}
+
+func sortPolicyNodes(nodes []*PolicyNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Policy.Name < nodes[j].Policy.Name
+	})
+}
+
+// Get returns the node of a policy.
+func (tree *PolicyTree) Get(policyID int) (node *PolicyNode, ok bool) {
+	node, ok = tree.nodes[policyID]
+	return
+}
+
+// Children returns the policies directly inheriting from a policy.
+func (tree *PolicyTree) Children(policyID int) (children []Policy) {
+	if node, ok := tree.nodes[policyID]; ok {
+		for _, child := range node.Children {
+			children = append(children, child.Policy)
+		}
+	}
+	return
+}
+
+// Ancestors returns the parents of a policy, nearest first.
+func (tree *PolicyTree) Ancestors(policyID int) (ancestors []Policy) {
+	node, ok := tree.nodes[policyID]
+	if !ok {
+		return
+	}
+
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		ancestors = append(ancestors, parent.Policy)
+	}
+	return
+}
+
+// Descendants returns all the policies inheriting (directly or not) from a
+// policy, depth first.
+func (tree *PolicyTree) Descendants(policyID int) (descendants []Policy) {
+	node, ok := tree.nodes[policyID]
+	if !ok {
+		return
+	}
+
+	var walk func(node *PolicyNode)
+	walk = func(node *PolicyNode) {
+		for _, child := range node.Children {
+			descendants = append(descendants, child.Policy)
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return
+}
+
+// Explain which policy applies to a device and why: the device policy
+// assignment, the role→policy mapping of its organization, the policy
+// inheritance chain and the policy sections overridden on the device.
+func ExplainEffectivePolicy(ctx context.Context, device Device) (effective EffectivePolicy, err error) {
+	effective.DeviceID = device.ID
+
+	tree, err := listPolicyTree(ctx)
+	if err != nil {
+		err = fmt.Errorf("error listing policies: %w", err)
+		return
+	}
+
+	var organization OrganizationDetailed
+	err = requestContext(ctx, http.MethodGet, fmt.Sprintf("organization/%d", device.OrganizationID), nil, &organization)
+	if err != nil {
+		err = fmt.Errorf("error getting organization %d: %w", device.OrganizationID, err)
+		return
+	}
+
+	err = requestContext(ctx, http.MethodGet, fmt.Sprintf("device/%d/policy/overrides", device.ID), nil, &effective.Overrides)
+	if err != nil {
+		err = fmt.Errorf("error getting device %d policy overrides: %w", device.ID, err)
+		return
+	}
+
+	role, err := device.Role(ctx)
+	if err != nil {
+		err = fmt.Errorf("error getting device %d role: %w", device.ID, err)
+		return
+	}
+
+	effective.explain(device, role, organization, tree)
+	return
+}
+
+// Build the explanation of the effective policy
+func (effective *EffectivePolicy) explain(device Device, role DeviceRole, organization OrganizationDetailed, tree *PolicyTree) {
+	describe := func(policyID int) string {
+		if node, ok := tree.Get(policyID); ok {
+			return fmt.Sprintf("'%s' (%d)", node.Policy.Name, policyID)
+		}
+		return fmt.Sprintf("%d (unknown policy)", policyID)
+	}
+
+	roleName := fmt.Sprintf("%d", device.NodeRoleID)
+	if role.Name != "" {
+		roleName = fmt.Sprintf("'%s' (%d)", role.Name, role.ID)
+	}
+
+	for _, mapping := range organization.Policies {
+		if mapping.NodeRoleID == device.NodeRoleID {
+			effective.MappedPolicyID = mapping.PolicyID
+			effective.Reasons = append(effective.Reasons, fmt.Sprintf("role %s is mapped to policy %s in organization '%s' (%d)", roleName, describe(mapping.PolicyID), organization.Name, organization.ID))
+		}
+	}
+
+	if effective.MappedPolicyID == 0 {
+		effective.Reasons = append(effective.Reasons, fmt.Sprintf("role %s has no policy mapping in organization '%s' (%d)", roleName, organization.Name, organization.ID))
+	}
+
+	if device.RolePolicyID != 0 && effective.MappedPolicyID != 0 && device.RolePolicyID != effective.MappedPolicyID {
+		effective.Reasons = append(effective.Reasons, fmt.Sprintf("device reports role policy %s which differs from the organization mapping, the mapping change may not be applied yet", describe(device.RolePolicyID)))
+	}
+
+	switch {
+	case device.PolicyID != 0 && device.PolicyID != device.RolePolicyID:
+		effective.Source = PolicySourceDevice
+		effective.Reasons = append(effective.Reasons, fmt.Sprintf("device policy %s is assigned directly and replaces the role policy", describe(device.PolicyID)))
+	case device.RolePolicyID != 0:
+		effective.Source = PolicySourceOrganizationRole
+		effective.Reasons = append(effective.Reasons, fmt.Sprintf("device uses its role policy %s", describe(device.RolePolicyID)))
+	case effective.MappedPolicyID != 0:
+		effective.Source = PolicySourceOrganizationRole
+		effective.Reasons = append(effective.Reasons, "device uses the organization role mapping")
+	default:
+		effective.Source = PolicySourceNone
+		effective.Reasons = append(effective.Reasons, "no policy applies to the device")
+	}
+
+	policyID := device.PolicyID
+	if effective.Source == PolicySourceOrganizationRole {
+		policyID = device.RolePolicyID
+		if policyID == 0 {
+			policyID = effective.MappedPolicyID
+		}
+	}
+
+	if node, ok := tree.Get(policyID); ok {
+		effective.Policy = node.Policy
+		effective.Ancestors = tree.Ancestors(policyID)
+
+		if len(effective.Ancestors) > 0 {
+			names := make([]string, len(effective.Ancestors))
+			for i, ancestor := range effective.Ancestors {
+				names[i] = fmt.Sprintf("'%s'", ancestor.Name)
+			}
+			effective.Reasons = append(effective.Reasons, fmt.Sprintf("policy '%s' inherits from %s", node.Policy.Name, strings.Join(names, " <- ")))
+		}
+	}
+
+	if len(effective.Overrides.Overrides) > 0 {
+		effective.Reasons = append(effective.Reasons, fmt.Sprintf("%d policy section(s) overridden on the device: %s", len(effective.Overrides.Overrides), strings.Join(effective.Overrides.Overrides, ", ")))
+	}
+}
+
+// String returns the explanation, one reason per line.
+func (effective EffectivePolicy) String() string {
+	return strings.Join(effective.Reasons, "\n")
+}
+
+// Policy inheritance tree
+type PolicyTree struct {
+	Roots []*PolicyNode // Policies without parent, sorted by name
+
+	nodes map[int]*PolicyNode
+}
+
+type PolicyNode struct {
+	Policy   Policy
+	Parent   *PolicyNode
+	Children []*PolicyNode // Sorted by name
+}
+
+// Policy applying to a device and the reasons why
+type EffectivePolicy struct {
+	DeviceID       int
+	Policy         Policy          // Policy applying to the device
+	Source         PolicySource    // Where the policy comes from
+	MappedPolicyID int             // Policy mapped to the device role in its organization, 0 if none
+	Ancestors      []Policy        // Policies inherited by Policy, nearest first
+	Overrides      PolicyOverrides // Policy sections overridden on the device
+	Reasons        []string        // Human readable explanation
+}
+
+type PolicySource string
+
+const (
+	PolicySourceNone             PolicySource = "NONE"
+	PolicySourceDevice           PolicySource = "DEVICE"
+	PolicySourceOrganizationRole PolicySource = "ORGANIZATION_ROLE"
+)
+
+// Error returned when policies inherit from each other in a loop
+type PolicyCycleError struct {
+	PolicyIDs []int // Policies in the inheritance chain up to the loop
+}
+
+func (e *PolicyCycleError) Error() string {
+	return fmt.Sprintf("policy inheritance cycle: %v", e.PolicyIDs)
+}
+
+// Is makes errors.Is(err, ErrPolicyCycle) true.
+func (e *PolicyCycleError) Is(target error) bool {
+	return target == ErrPolicyCycle
+}
@@ -0,0 +1,213 @@
+package ninjarmm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Compare the role→policy mappings of every organization against the ones
+// of a reference organization (e.g. the template used with
+// CreateOrganization). The reference organization is not in the report.
+func DetectPolicyDriftFromOrganization(referenceOrganizationID int) (report PolicyDriftReport, err error) {
+	organizations, err := ListOrganizationsDetailed()
+	if err != nil {
+		err = fmt.Errorf("error listing organizations: %w", err)
+		return
+	}
+
+	var reference []OrganizationPolicyItem
+	var others []OrganizationDetailed
+	found := false
+
+	for _, organization := range organizations {
+		if organization.ID == referenceOrganizationID {
+			reference = organization.Policies
+			found = true
+		} else {
+			others = append(others, organization)
+		}
+	}
+
+	if !found {
+		err = fmt.Errorf("reference organization %d not found", referenceOrganizationID)
+		return
+	}
+
+	return DetectPolicyDrift(reference, others), nil
+}
+
+// Compare the role→policy mappings of every organization against a declared
+// mapping.
+func DetectPolicyDriftFromMapping(reference []OrganizationPolicyItem) (report PolicyDriftReport, err error) {
+	organizations, err := ListOrganizationsDetailed()
+	if err != nil {
+		err = fmt.Errorf("error listing organizations: %w", err)
+		return
+	}
+
+	return DetectPolicyDrift(reference, organizations), nil
+}
+
+// DetectPolicyDrift compares the role→policy mappings of organizations
+// against a reference mapping.
+func DetectPolicyDrift(reference []OrganizationPolicyItem, organizations []OrganizationDetailed) (report PolicyDriftReport) {
+	report.Reference = reference
+	expected := policyMappingByRole(reference)
+
+	for _, organization := range organizations {
+		drift := PolicyMappingDrift{
+			OrganizationID:   organization.ID,
+			OrganizationName: organization.Name,
+			Current:          organization.Policies,
+		}
+
+		actual := policyMappingByRole(organization.Policies)
+
+		for roleID, policyID := range expected {
+			actualPolicyID, ok := actual[roleID]
+			if !ok {
+				drift.Missing = append(drift.Missing, OrganizationPolicyItem{NodeRoleID: roleID, PolicyID: policyID})
+			} else if actualPolicyID != policyID {
+				drift.Mismatched = append(drift.Mismatched, PolicyMappingMismatch{
+					NodeRoleID:       roleID,
+					ExpectedPolicyID: policyID,
+					ActualPolicyID:   actualPolicyID,
+				})
+			}
+		}
+
+		for roleID, policyID := range actual {
+			if _, ok := expected[roleID]; !ok {
+				drift.Extra = append(drift.Extra, OrganizationPolicyItem{NodeRoleID: roleID, PolicyID: policyID})
+			}
+		}
+
+		sortPolicyItems(drift.Missing)
+		sortPolicyItems(drift.Extra)
+		sort.Slice(drift.Mismatched, func(i, j int) bool {
+			return drift.Mismatched[i].NodeRoleID < drift.Mismatched[j].NodeRoleID
+		})
+
+		report.Organizations = append(report.Organizations, drift)
+	}
+
+	sort.Slice(report.Organizations, func(i, j int) bool {
+		return report.Organizations[i].OrganizationID < report.Organizations[j].OrganizationID
+	})
+
+	return
+}
+
+// Drifted returns the organizations whose mappings differ from the reference.
+func (report PolicyDriftReport) Drifted() (drifts []PolicyMappingDrift) {
+	for _, drift := range report.Organizations {
+		if drift.HasDrift() {
+			drifts = append(drifts, drift)
+		}
+	}
+	return
+}
+
+// Remediate sets the reference mappings on every drifted organization with
+// UpdateOrganizationPolicies. Extra mappings are kept unless
+// `options.RemoveExtra` is set. With `options.DryRun` nothing is sent and
+// the results only hold the mappings that would be sent.
+func (report PolicyDriftReport) Remediate(options PolicyRemediationOptions) (results []PolicyRemediation) {
+	for _, drift := range report.Drifted() {
+		if !options.RemoveExtra && len(drift.Missing) == 0 && len(drift.Mismatched) == 0 {
+			continue
+		}
+
+		result := PolicyRemediation{
+			OrganizationID: drift.OrganizationID,
+			Policies:       drift.desiredPolicies(report.Reference, options.RemoveExtra),
+			DryRun:         options.DryRun,
+		}
+
+		if !options.DryRun {
+			result.AffectedDeviceIDs, result.Err = UpdateOrganizationPolicies(drift.OrganizationID, result.Policies)
+		}
+
+		results = append(results, result)
+	}
+
+	return
+}
+
+// Mappings to send to remove the drift
+func (drift PolicyMappingDrift) desiredPolicies(reference []OrganizationPolicyItem, removeExtra bool) (policies []OrganizationPolicyItem) {
+	desired := policyMappingByRole(reference)
+
+	if !removeExtra {
+		for _, extra := range drift.Extra {
+			desired[extra.NodeRoleID] = extra.PolicyID
+		}
+	}
+
+	for roleID, policyID := range desired {
+		policies = append(policies, OrganizationPolicyItem{NodeRoleID: roleID, PolicyID: policyID})
+	}
+
+	sortPolicyItems(policies)
+	return
+}
+
+// HasDrift returns true if the organization mappings differ from the
+// reference.
+func (drift PolicyMappingDrift) HasDrift() bool {
+	return len(drift.Missing) > 0 || len(drift.Extra) > 0 || len(drift.Mismatched) > 0
+}
+
+func policyMappingByRole(policies []OrganizationPolicyItem) map[int]int {
+	mapping := make(map[int]int, len(policies))
+	for _, item := range policies {
+		mapping[item.NodeRoleID] = item.PolicyID
+	}
+	return mapping
+}
+
+func sortPolicyItems(items []OrganizationPolicyItem) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].NodeRoleID < items[j].NodeRoleID
+	})
+}
+
+// Drift of the role→policy mappings of organizations against a reference
+type PolicyDriftReport struct {
+	Reference     []OrganizationPolicyItem
+	Organizations []PolicyMappingDrift // Sorted by organization ID
+}
+
+// Drift of the role→policy mappings of an organization
+type PolicyMappingDrift struct {
+	OrganizationID   int
+	OrganizationName string
+	Current          []OrganizationPolicyItem
+	Missing          []OrganizationPolicyItem // In the reference only
+	Extra            []OrganizationPolicyItem // In the organization only
+	Mismatched       []PolicyMappingMismatch  // Role mapped to another policy
+}
+
+type PolicyMappingMismatch struct {
+	NodeRoleID       int
+	ExpectedPolicyID int
+	ActualPolicyID   int
+}
+
+// Options for PolicyDriftReport.Remediate
+type PolicyRemediationOptions struct {
+	// Only compute the mappings, nothing is sent
+	DryRun bool
+
+	// Drop the mappings of roles missing from the reference
+	RemoveExtra bool
+}
+
+// Result of the remediation of an organization
+type PolicyRemediation struct {
+	OrganizationID    int
+	Policies          []OrganizationPolicyItem // Mappings sent (or to send in dry-run)
+	DryRun            bool
+	AffectedDeviceIDs []int
+	Err               error
+}
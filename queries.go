@@ -4,125 +4,312 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
+// Run a query (report) on devices, returns one page of results.
+//
+// T must match the results of the endpoint, see the Query* functions for
+// the typed shortcuts.
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/articles/queries
+//
+// Usage:
+//
+//	report, err := ninjarmm.Query[ninjarmm.WindowsService](ninjarmm.QueryEndpointWindowsServices, ninjarmm.QueryOptions{
+//		DeviceFilter: "org = 1",
+//		PageSize:     100,
+//	})
+func Query[T any](endpoint QueryEndpoint, options QueryOptions) (report Report[T], err error) {
+	err = request(http.MethodGet, string(endpoint)+"?"+options.queryString(), nil, &report)
+	return
+}
+
+// Run a query (report) on devices and follow the cursor until all results
+// are fetched.
+func QueryAll[T any](endpoint QueryEndpoint, options QueryOptions) (results []T, err error) {
+	for {
+		var report Report[T]
+		report, err = Query[T](endpoint, options)
+		if err != nil {
+			return
+		}
+
+		results = append(results, report.Results...)
+
+		if len(report.Results) == 0 || report.Cursor.Name == "" || (options.PageSize > 0 && len(report.Results) < options.PageSize) {
+			return
+		}
+
+		options.Cursor = report.Cursor.Name
+	}
+}
+
 // Query computer systems device informations
 //
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getComputerSystems
 func QueryComputerSystems(filter string, pageSize int) (report ComputerSystemReport, err error) {
-	urlValues := url.Values{}
+	return Query[ComputerSystem](QueryEndpointComputerSystems, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if filter != "" {
-		urlValues.Set("df", filter)
-	}
+// Query operating systems device informations
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getOperatingSystems
+func QueryOperatingSystems(filter string, pageSize int) (report OperatingSystemReport, err error) {
+	return Query[OperatingSystem](QueryEndpointOperatingSystems, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if pageSize != 0 {
-		urlValues.Set("pageSize", fmt.Sprint(pageSize))
-	}
+// Query processors device informations
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getProcessors
+func QueryProcessorReport(filter string, pageSize int) (report ProcessorReport, err error) {
+	return Query[ProcessorInfo](QueryEndpointProcessors, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	err = request(http.MethodGet, "queries/computer-systems?"+urlValues.Encode(), nil, &report)
-	return
+// Query disk volumes device informations
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getVolumes
+func QueryDiskVolumesReport(filter string, pageSize int) (report DiskVolumesReport, err error) {
+	return Query[DiskVolumes](QueryEndpointVolumes, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
 }
 
-type ComputerSystemReport struct {
-	Cursor  ReportCursor     `json:"cursor"`
-	Results []ComputerSystem `json:"results"`
+// Query installed software
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getSoftware
+func SoftwareInventory(filter string, pageSize int) (report SoftwareInventoryReport, err error) {
+	return Query[Software](QueryEndpointSoftware, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
 }
 
-type ComputerSystem struct {
-	Name                string `json:"name"`
-	Manufacturer        string `json:"manufacturer"`
-	Model               string `json:"model"`
-	BiosSerialNumber    string `json:"biosSerialNumber"`
-	SerialNumber        string `json:"serialNumber"`
-	Domain              string `json:"domain"`
-	DomainRole          string `json:"domainRole"`
-	NumberOfProcessors  int    `json:"numberOfProcessors"`
-	TotalPhysicalMemory int    `json:"totalPhysicalMemory"`
-	VirtualMachine      bool   `json:"virtualMachine"`
-	ChassisType         string `json:"chassisType"`
-	DeviceID            int    `json:"deviceId"`
-	Timestamp           Time   `json:"timestamp"`
+// Query antivirus products status
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getAntivirusStatus
+func QueryAntivirusStatus(filter string, pageSize int) (report Report[AntivirusStatus], err error) {
+	return Query[AntivirusStatus](QueryEndpointAntivirusStatus, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
 }
 
-// Query operating systems device informations
+// Query antivirus threats
 //
-// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getOperatingSystems
-func QueryOperatingSystems(filter string, pageSize int) (report OperatingSystemReport, err error) {
-	urlValues := url.Values{}
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getAntivirusThreats
+func QueryAntivirusThreats(filter string, pageSize int) (report Report[AntivirusThreat], err error) {
+	return Query[AntivirusThreat](QueryEndpointAntivirusThreats, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if filter != "" {
-		urlValues.Set("df", filter)
-	}
+// Query device health
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceHealth
+func QueryDeviceHealth(filter string, pageSize int) (report Report[DeviceHealth], err error) {
+	return Query[DeviceHealth](QueryEndpointDeviceHealth, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if pageSize != 0 {
-		urlValues.Set("pageSize", fmt.Sprint(pageSize))
-	}
+// Query logged on users
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getLoggedOnUsers
+func QueryLoggedOnUsers(filter string, pageSize int) (report Report[LoggedOnUser], err error) {
+	return Query[LoggedOnUser](QueryEndpointLoggedOnUsers, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	err = request(http.MethodGet, "queries/operating-systems?"+urlValues.Encode(), nil, &report)
-	return
+// Query network interfaces
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getNetworkInterfaces
+func QueryNetworkInterfaces(filter string, pageSize int) (report Report[NetworkInterface], err error) {
+	return Query[NetworkInterface](QueryEndpointNetworkInterfaces, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
 }
 
-func QueryProcessorReport(filter string, pageSize int) (report ProcessorReport, err error) {
-	urlValues := url.Values{}
+// Query pending, failed and rejected OS patches
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getOsPatches
+func QueryOSPatches(filter string, pageSize int) (report Report[OSPatch], err error) {
+	return Query[OSPatch](QueryEndpointOSPatches, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if filter != "" {
-		urlValues.Set("df", filter)
-	}
+// Query installed OS patches
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getOsPatchInstalls
+func QueryOSPatchInstalls(filter string, pageSize int) (report Report[OSPatch], err error) {
+	return Query[OSPatch](QueryEndpointOSPatchInstalls, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if pageSize != 0 {
-		urlValues.Set("pageSize", fmt.Sprint(pageSize))
-	}
+// Query pending, failed and rejected software patches
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getSoftwarePatches
+func QuerySoftwarePatches(filter string, pageSize int) (report Report[SoftwarePatch], err error) {
+	return Query[SoftwarePatch](QueryEndpointSoftwarePatches, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	err = request(http.MethodGet, "queries/processor-report?"+urlValues.Encode(), nil, &report)
-	return
+// Query installed software patches
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getSoftwarePatchInstalls
+func QuerySoftwarePatchInstalls(filter string, pageSize int) (report Report[SoftwarePatch], err error) {
+	return Query[SoftwarePatch](QueryEndpointSoftwarePatchInstalls, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
 }
 
-func QueryDiskVolumesReport(filter string, pageSize int) (report DiskVolumesReport, err error) {
-	urlValues := url.Values{}
+// Query RAID controllers
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getRaidControllers
+func QueryRAIDControllers(filter string, pageSize int) (report Report[RAIDController], err error) {
+	return Query[RAIDController](QueryEndpointRAIDControllers, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
 
-	if filter != "" {
-		urlValues.Set("df", filter)
+// Query RAID drives
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getRaidDrives
+func QueryRAIDDrives(filter string, pageSize int) (report Report[RAIDDrive], err error) {
+	return Query[RAIDDrive](QueryEndpointRAIDDrives, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
+
+// Query Windows services
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getWindowsServices
+func QueryWindowsServices(filter string, pageSize int) (report Report[WindowsService], err error) {
+	return Query[WindowsService](QueryEndpointWindowsServices, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
+
+// Query physical disks
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDisks
+func QueryDisks(filter string, pageSize int) (report Report[Disk], err error) {
+	return Query[Disk](QueryEndpointDisks, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
+
+// Query backup usage
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getBackupUsage
+func QueryBackupUsage(filter string, pageSize int) (report Report[BackupUsage], err error) {
+	return Query[BackupUsage](QueryEndpointBackupUsage, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
+
+// Query policy overrides
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getPolicyOverrides
+func QueryPolicyOverrides(filter string, pageSize int) (report Report[PolicyOverrides], err error) {
+	return Query[PolicyOverrides](QueryEndpointPolicyOverrides, QueryOptions{DeviceFilter: filter, PageSize: pageSize})
+}
+
+// Query custom fields values, `fields` limits the returned custom fields
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceCustomFields
+func QueryCustomFields(filter string, fields []string, pageSize int) (report Report[DeviceCustomFields], err error) {
+	return Query[DeviceCustomFields](QueryEndpointCustomFields, QueryOptions{DeviceFilter: filter, PageSize: pageSize, Fields: fields})
+}
+
+// Options for Query and QueryAll
+type QueryOptions struct {
+	// Device filter (See https://eu.ninjarmm.com/apidocs-beta/core-resources/articles/devices/device-filters)
+	DeviceFilter string
+
+	// Limit number of results to return
+	PageSize int
+
+	// Cursor name from a previous report, to get the next page
+	Cursor string
+
+	// Return results recorded after the specified timestamp (unix seconds or ISO date)
+	Timestamp string
+
+	// Custom fields to return (custom fields query only)
+	Fields []string
+
+	// Additional endpoint specific parameters (e.g. 'status' for patches)
+	Extra url.Values
+}
+
+// Internal function to convert `QueryOptions` to url query string
+func (options QueryOptions) queryString() string {
+	values := url.Values{}
+
+	for key, value := range options.Extra {
+		values[key] = value
 	}
 
-	if pageSize != 0 {
-		urlValues.Set("pageSize", fmt.Sprint(pageSize))
+	if options.DeviceFilter != "" {
+		values.Set("df", options.DeviceFilter)
 	}
 
-	err = request(http.MethodGet, "queries/volumes?"+urlValues.Encode(), nil, &report)
-	return
-}
+	if options.PageSize != 0 {
+		values.Set("pageSize", fmt.Sprint(options.PageSize))
+	}
 
-func SoftwareInventory(filter string, pageSize int) (report SoftwareInventoryReport, err error) {
-	urlValues := url.Values{}
+	if options.Cursor != "" {
+		values.Set("cursor", options.Cursor)
+	}
 
-	if filter != "" {
-		urlValues.Set("df", filter)
+	if options.Timestamp != "" {
+		values.Set("ts", options.Timestamp)
 	}
 
-	if pageSize != 0 {
-		urlValues.Set("pageSize", fmt.Sprint(pageSize))
+	if len(options.Fields) > 0 {
+		values.Set("fields", strings.Join(options.Fields, ","))
 	}
 
-	err = request(http.MethodGet, "queries/software?"+urlValues.Encode(), nil, &report)
-	return
+	return values.Encode()
 }
 
-type ProcessorReport struct {
-	Cursor  ReportCursor    `json:"cursor"`
-	Results []ProcessorInfo `json:"results"`
+type QueryEndpoint string
+
+const (
+	QueryEndpointComputerSystems       QueryEndpoint = "queries/computer-systems"
+	QueryEndpointOperatingSystems      QueryEndpoint = "queries/operating-systems"
+	QueryEndpointProcessors            QueryEndpoint = "queries/processor-report"
+	QueryEndpointVolumes               QueryEndpoint = "queries/volumes"
+	QueryEndpointSoftware              QueryEndpoint = "queries/software"
+	QueryEndpointAntivirusStatus       QueryEndpoint = "queries/antivirus-status"
+	QueryEndpointAntivirusThreats      QueryEndpoint = "queries/antivirus-threats"
+	QueryEndpointDeviceHealth          QueryEndpoint = "queries/device-health"
+	QueryEndpointLoggedOnUsers         QueryEndpoint = "queries/logged-on-users"
+	QueryEndpointNetworkInterfaces     QueryEndpoint = "queries/network-interfaces"
+	QueryEndpointOSPatches             QueryEndpoint = "queries/os-patches"
+	QueryEndpointOSPatchInstalls       QueryEndpoint = "queries/os-patch-installs"
+	QueryEndpointSoftwarePatches       QueryEndpoint = "queries/software-patches"
+	QueryEndpointSoftwarePatchInstalls QueryEndpoint = "queries/software-patch-installs"
+	QueryEndpointRAIDControllers       QueryEndpoint = "queries/raid-controllers"
+	QueryEndpointRAIDDrives            QueryEndpoint = "queries/raid-drives"
+	QueryEndpointWindowsServices       QueryEndpoint = "queries/windows-services"
+	QueryEndpointDisks                 QueryEndpoint = "queries/disks"
+	QueryEndpointBackupUsage           QueryEndpoint = "queries/backup/usage"
+	QueryEndpointPolicyOverrides       QueryEndpoint = "queries/policy-overrides"
+	QueryEndpointCustomFields          QueryEndpoint = "queries/custom-fields"
+)
+
+// Generic report returned by the queries
+type Report[T any] struct {
+	Cursor  ReportCursor `json:"cursor"`
+	Results []T          `json:"results"`
 }
 
-type ProcessorInfo struct {
-	Architecture  string `json:"architecture"`
-	MaxClockSpeed int    `json:"maxClockSpeed"`
+type ComputerSystemReport = Report[ComputerSystem]
+
+type ComputerSystem struct {
+	Name                string `json:"name"`
+	Manufacturer        string `json:"manufacturer"`
+	Model               string `json:"model"`
+	BiosSerialNumber    string `json:"biosSerialNumber"`
+	SerialNumber        string `json:"serialNumber"`
+	Domain              string `json:"domain"`
+	DomainRole          string `json:"domainRole"`
+	NumberOfProcessors  int    `json:"numberOfProcessors"`
+	TotalPhysicalMemory int    `json:"totalPhysicalMemory"`
+	VirtualMachine      bool   `json:"virtualMachine"`
+	ChassisType         string `json:"chassisType"`
+	DeviceID            int    `json:"deviceId"`
+	Timestamp           Time   `json:"timestamp"`
 }
 
-type OperatingSystemReport struct {
-	Cursor  ReportCursor      `json:"cursor"`
-	Results []OperatingSystem `json:"results"`
+type ProcessorReport = Report[ProcessorInfo]
+
+type ProcessorInfo struct {
+	Name            string `json:"name"`
+	Manufacturer    string `json:"manufacturer"`
+	Architecture    string `json:"architecture"`
+	MaxClockSpeed   int    `json:"maxClockSpeed"`
+	ClockSpeed      int    `json:"clockSpeed"`
+	NumCores        int    `json:"numCores"`
+	NumLogicalCores int    `json:"numLogicalCores"`
+	DeviceID        int    `json:"deviceId"`
+	Timestamp       Time   `json:"timestamp"`
 }
 
+type OperatingSystemReport = Report[OperatingSystem]
+
 type OperatingSystem struct {
 	Name                    string `json:"name"`
 	Manufacturer            string `json:"manufacturer"`
@@ -147,10 +334,7 @@ type ReportCursor struct {
 	Expires Time   `json:"expires"`
 }
 
-type DiskVolumesReport struct {
-	Cursor  ReportCursor  `json:"cursor"`
-	Results []DiskVolumes `json:"results"`
-}
+type DiskVolumesReport = Report[DiskVolumes]
 
 type DiskVolumes struct {
 	Name            string `json:"name"`
@@ -174,10 +358,7 @@ type DiskVolumes struct {
 	Timestamp int `json:"timestamp"`
 }
 
-type SoftwareInventoryReport struct {
-	Cursor  ReportCursor `json:"cursor"`
-	Results []Software   `json:"results"`
-}
+type SoftwareInventoryReport = Report[Software]
 
 type Software struct {
 	Location    string `json:"location"`
@@ -188,3 +369,97 @@ type Software struct {
 	ProductCode string `json:"productCode"`
 	DeviceId    int    `json:"deviceId"`
 }
+
+type AntivirusStatus struct {
+	ProductName      string `json:"productName"`
+	ProductState     string `json:"productState"`
+	DefinitionStatus string `json:"definitionStatus"`
+	Version          string `json:"version"`
+	DeviceID         int    `json:"deviceId"`
+	Timestamp        Time   `json:"timestamp"`
+}
+
+type AntivirusThreat struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Type        string `json:"type"`
+	Category    string `json:"category"`
+	Level       string `json:"level"`
+	Certainty   string `json:"certainty"`
+	Path        string `json:"path"`
+	ProductName string `json:"productName"`
+	DeviceID    int    `json:"deviceId"`
+	Timestamp   Time   `json:"timestamp"`
+}
+
+type DeviceHealth struct {
+	DeviceID                        int            `json:"deviceId"`
+	ParentDeviceID                  int            `json:"parentDeviceId"`
+	HealthStatus                    string         `json:"healthStatus"`
+	Offline                         bool           `json:"offline"`
+	AlertCount                      int            `json:"alertCount"`
+	ActiveThreatsCount              int            `json:"activeThreatsCount"`
+	QuarantinedThreatsCount         int            `json:"quarantinedThreatsCount"`
+	BlockedThreatsCount             int            `json:"blockedThreatsCount"`
+	FailedOSPatchesCount            int            `json:"failedOSPatchesCount"`
+	PendingOSPatchesCount           int            `json:"pendingOSPatchesCount"`
+	InstalledOSPatchesCount         int            `json:"installedOSPatchesCount"`
+	FailedSoftwarePatchesCount      int            `json:"failedSoftwarePatchesCount"`
+	PendingSoftwarePatchesCount     int            `json:"pendingSoftwarePatchesCount"`
+	InstalledSoftwarePatchesCount   int            `json:"installedSoftwarePatchesCount"`
+	PendingRebootReason             string         `json:"pendingRebootReason"`
+	ProductsInstallationStatuses    map[string]any `json:"productsInstallationStatuses"`
+	ActiveJobsCount                 int            `json:"activeJobsCount"`
+	FailedBackupsCount              int            `json:"failedBackupsCount"`
+	ActiveAlertsCount               int            `json:"activeAlertsCount"`
+	UnhealthyProductsCount          int            `json:"unhealthyProductsCount"`
+	PartiallyUnhealthyProductsCount int            `json:"partiallyUnhealthyProductsCount"`
+	Timestamp                       Time           `json:"timestamp"`
+}
+
+type RAIDController struct {
+	Name            string         `json:"name"`
+	Manufacturer    string         `json:"manufacturer"`
+	Model           string         `json:"model"`
+	SerialNumber    string         `json:"serialNumber"`
+	FirmwareVersion string         `json:"firmwareVersion"`
+	DriverVersion   string         `json:"driverVersion"`
+	Status          string         `json:"status"`
+	Data            map[string]any `json:"data"`
+	DeviceID        int            `json:"deviceId"`
+	Timestamp       Time           `json:"timestamp"`
+}
+
+type RAIDDrive struct {
+	Name          string         `json:"name"`
+	Manufacturer  string         `json:"manufacturer"`
+	Model         string         `json:"model"`
+	SerialNumber  string         `json:"serialNumber"`
+	MediaType     string         `json:"mediaType"`
+	InterfaceType string         `json:"interfaceType"`
+	Capacity      int            `json:"capacity"`
+	Status        string         `json:"status"`
+	SmartStatus   string         `json:"smartStatus"`
+	Data          map[string]any `json:"data"`
+	DeviceID      int            `json:"deviceId"`
+	Timestamp     Time           `json:"timestamp"`
+}
+
+type BackupUsage struct {
+	DeviceID   int    `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+	Revisions  struct {
+		TotalSize   int `json:"totalSize"`
+		CloudSize   int `json:"cloudSize"`
+		LocalSize   int `json:"localSize"`
+		DeletedSize int `json:"deletedSize"`
+	} `json:"revisionsSize"`
+	Timestamp Time `json:"timestamp"`
+}
+
+type DeviceCustomFields struct {
+	DeviceID  int          `json:"deviceId"`
+	Fields    CustomFields `json:"fields"`
+	Timestamp Time         `json:"timestamp"`
+}
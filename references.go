@@ -0,0 +1,276 @@
+package ninjarmm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Identity maps shared by all devices, filled by listing all the entities
+// of a kind at once so that resolving the references of many devices costs
+// one request per kind
+var (
+	organizationsByID = newIdentityMap("organization", func(ctx context.Context) (items []Organization, err error) {
+		err = requestContext(ctx, http.MethodGet, "organizations", nil, &items)
+		return
+	}, func(o Organization) int { return o.ID })
+
+	locationsByID = newIdentityMap("location", listAllLocations, func(l Location) int { return l.ID })
+
+	policiesByID = newIdentityMap("policy", func(ctx context.Context) (items []Policy, err error) {
+		err = requestContext(ctx, http.MethodGet, "policies", nil, &items)
+		return
+	}, func(p Policy) int { return p.ID })
+
+	rolesByID = newIdentityMap("role", func(ctx context.Context) (items []DeviceRole, err error) {
+		err = requestContext(ctx, http.MethodGet, "roles", nil, &items)
+		return
+	}, func(r DeviceRole) int { return r.ID })
+)
+
+// Returns the organization of the device, from References when populated,
+// otherwise from the shared identity map.
+func (device Device) Organization(ctx context.Context) (organization Organization, err error) {
+	if device.References.Organization.ID == device.OrganizationID && device.OrganizationID != 0 {
+		return device.References.Organization, nil
+	}
+	return organizationsByID.get(ctx, "organization", device.OrganizationID)
+}
+
+// Returns the location of the device, from References when populated,
+// otherwise from the shared identity map.
+func (device Device) Location(ctx context.Context) (location Location, err error) {
+	if device.References.Location.ID == device.LocationID && device.LocationID != 0 {
+		return device.References.Location, nil
+	}
+	return locationsByID.get(ctx, "location", device.LocationID)
+}
+
+// Returns the policy applied to the device: the device policy if set,
+// otherwise the policy of its role in the organization.
+func (device Device) Policy(ctx context.Context) (policy Policy, err error) {
+	if device.PolicyID == 0 {
+		return device.RolePolicy(ctx)
+	}
+
+	if device.References.Policy.ID == device.PolicyID {
+		return device.References.Policy, nil
+	}
+	return policiesByID.get(ctx, "policy", device.PolicyID)
+}
+
+// Returns the policy mapped to the device role in its organization.
+func (device Device) RolePolicy(ctx context.Context) (policy Policy, err error) {
+	if device.References.RolePolicy.ID == device.RolePolicyID && device.RolePolicyID != 0 {
+		return device.References.RolePolicy, nil
+	}
+	return policiesByID.get(ctx, "policy", device.RolePolicyID)
+}
+
+// Returns the role of the device.
+func (device Device) Role(ctx context.Context) (role DeviceRole, err error) {
+	if device.References.Role.ID == device.NodeRoleID && device.NodeRoleID != 0 {
+		return device.References.Role, nil
+	}
+	return rolesByID.get(ctx, "role", device.NodeRoleID)
+}
+
+// Empty the identity maps used by the Device reference methods, use it
+// after organizations, locations, policies or roles were changed outside
+// of the package. Changes made with the package clear them.
+func ClearReferences() {
+	organizationsByID.clear()
+	locationsByID.clear()
+	policiesByID.clear()
+	rolesByID.clear()
+}
+
+// Empty the identity maps affected by a mutation on `path`, see
+// invalidateCacheFor
+func invalidateReferencesFor(path string) {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+
+	var kinds []string
+	switch segments[0] {
+	case "organization", "organizations":
+		kinds = append(kinds, "organization")
+		if len(segments) > 2 && strings.HasPrefix(segments[2], "location") {
+			kinds = append(kinds, "location")
+		}
+	case "roles", "role":
+		kinds = append(kinds, "role")
+	case "policies", "policy":
+		kinds = append(kinds, "policy")
+	}
+
+	identityMapsMu.Lock()
+	defer identityMapsMu.Unlock()
+
+	for _, kind := range kinds {
+		for _, m := range identityMaps[kind] {
+			m.clear()
+		}
+	}
+}
+
+// List all locations of all organizations, following pages
+func listAllLocations(ctx context.Context) (locations []Location, err error) {
+	const pageSize = 1000
+	after := 0
+
+	for {
+		values := url.Values{
+			"pageSize": {fmt.Sprint(pageSize)},
+		}
+
+		if after != 0 {
+			values.Set("after", fmt.Sprint(after))
+		}
+
+		var page []Location
+		err = requestContext(ctx, http.MethodGet, "locations?"+values.Encode(), nil, &page)
+		if err != nil {
+			return
+		}
+
+		locations = append(locations, page...)
+
+		if len(page) < pageSize {
+			return
+		}
+
+		after = page[len(page)-1].ID
+	}
+}
+
+var (
+	// Identity maps by kind, a registry rather than the variables above so
+	// that requests can clear them without initialization cycle
+	identityMaps   map[string][]interface{ clear() }
+	identityMapsMu sync.Mutex
+)
+
+// Entities of a kind by ID, loaded all at once
+type identityMap[T any] struct {
+	mu      sync.Mutex
+	items   map[int]T
+	missing map[int]bool  // IDs not found after a reload
+	loading chan struct{} // Closed when the running reload ends, nil if none
+	loads   int           // Successful reloads, to detect a reload while waiting
+	cleared int           // Clears, to drop a reload started before a clear
+	list    func(ctx context.Context) ([]T, error)
+	idOf    func(T) int
+}
+
+// Create an identity map of entities of a `kind`, cleared by the mutations
+// of that kind (see invalidateReferencesFor)
+func newIdentityMap[T any](kind string, list func(ctx context.Context) ([]T, error), idOf func(T) int) *identityMap[T] {
+	m := &identityMap[T]{list: list, idOf: idOf}
+
+	identityMapsMu.Lock()
+	defer identityMapsMu.Unlock()
+	if identityMaps == nil {
+		identityMaps = make(map[string][]interface{ clear() })
+	}
+	identityMaps[kind] = append(identityMaps[kind], m)
+
+	return m
+}
+
+// Get an entity by ID, (re)loading the map when the ID is unknown. IDs
+// still unknown after a reload are remembered as missing until the map is
+// cleared. A zero ID returns a zero value without error.
+func (m *identityMap[T]) get(ctx context.Context, kind string, id int) (item T, err error) {
+	if id == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	for {
+		if item, ok := m.items[id]; ok {
+			m.mu.Unlock()
+			return item, nil
+		}
+
+		if m.missing[id] {
+			m.mu.Unlock()
+			err = fmt.Errorf("%s %d not found", kind, id)
+			return
+		}
+
+		if m.loading == nil {
+			break
+		}
+
+		// Another goroutine is reloading the map, wait for it
+		loading, loads := m.loading, m.loads
+		m.mu.Unlock()
+
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+
+		m.mu.Lock()
+		if _, ok := m.items[id]; !ok && m.loads > loads {
+			m.markMissing(id)
+		}
+	}
+
+	// Unknown ID: the map is not loaded yet or the entity is new
+	loading, cleared := make(chan struct{}), m.cleared
+	m.loading = loading
+	m.mu.Unlock()
+
+	items, err := m.list(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loading = nil
+	close(loading)
+
+	if err != nil {
+		err = fmt.Errorf("error listing %s references: %w", kind, err)
+		return
+	}
+
+	loaded := make(map[int]T, len(items))
+	for _, i := range items {
+		loaded[m.idOf(i)] = i
+	}
+
+	// Keep the result of a reload started before a clear for this call only
+	if m.cleared == cleared {
+		m.items, m.missing = loaded, nil
+		m.loads++
+	}
+
+	item, ok := loaded[id]
+	if !ok {
+		if m.cleared == cleared {
+			m.markMissing(id)
+		}
+		err = fmt.Errorf("%s %d not found", kind, id)
+	}
+	return
+}
+
+func (m *identityMap[T]) markMissing(id int) {
+	if m.missing == nil {
+		m.missing = make(map[int]bool)
+	}
+	m.missing[id] = true
+}
+
+func (m *identityMap[T]) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items, m.missing = nil, nil
+	m.cleared++
+}
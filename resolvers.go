@@ -0,0 +1,201 @@
+package ninjarmm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Returned (wrapped in a *NameNotFoundError) when no entity matches a name
+	ErrNameNotFound = errors.New("name not found")
+
+	// Returned (wrapped in an *AmbiguousNameError) when several entities match a name
+	ErrAmbiguousName = errors.New("ambiguous name")
+)
+
+// Find an organization by name.
+//
+// Matching is exact first, then case and space insensitive, then fuzzy
+// (partial names and small typos). When several organizations match at the
+// same level an *AmbiguousNameError listing them is returned.
+//
+// Enable the response cache (see EnableCache) to avoid listing the
+// organizations on each call.
+func ResolveOrganization(name string) (organization Organization, err error) {
+	organizations, err := ListOrganizations()
+	if err != nil {
+		return
+	}
+
+	return resolveByName("organization", name, organizations, func(o Organization) string { return o.Name })
+}
+
+// Find a location of an organization by name.
+//
+// See ResolveOrganization for the matching rules.
+func ResolveLocation(organizationID int, name string) (location Location, err error) {
+	locations, err := ListOrganizationLocations(organizationID)
+	if err != nil {
+		return
+	}
+
+	return resolveByName("location", name, locations, func(l Location) string { return l.Name })
+}
+
+// Find a device role by name, optionally restricted to a node class.
+//
+// See ResolveOrganization for the matching rules.
+func ResolveRole(name string, nodeClass NodeClass) (role DeviceRole, err error) {
+	roles, err := ListDeviceRoles()
+	if err != nil {
+		return
+	}
+
+	if nodeClass != "" {
+		filtered := roles[:0:0]
+		for _, role := range roles {
+			if role.NodeClass == nodeClass {
+				filtered = append(filtered, role)
+			}
+		}
+		roles = filtered
+	}
+
+	return resolveByName("role", name, roles, func(r DeviceRole) string { return r.Name })
+}
+
+// Find a device policy by name.
+//
+// See ResolveOrganization for the matching rules.
+func ResolvePolicy(name string) (policy Policy, err error) {
+	policies, err := ListDevicePolicies()
+	if err != nil {
+		return
+	}
+
+	return resolveByName("policy", name, policies, func(p Policy) string { return p.Name })
+}
+
+// Find the single item named `query`, trying exact, normalized and fuzzy
+// matching in this order
+func resolveByName[T any](kind, query string, items []T, name func(T) string) (found T, err error) {
+	normalizedQuery := normalizeName(query)
+
+	matchers := []func(candidate string) bool{
+		func(candidate string) bool { return candidate == query },
+		func(candidate string) bool { return normalizeName(candidate) == normalizedQuery },
+		func(candidate string) bool { return fuzzyNameMatch(normalizeName(candidate), normalizedQuery) },
+	}
+
+	for _, matches := range matchers {
+		var matched []T
+		for _, item := range items {
+			if matches(name(item)) {
+				matched = append(matched, item)
+			}
+		}
+
+		switch len(matched) {
+		case 0:
+			continue
+		case 1:
+			return matched[0], nil
+		default:
+			names := make([]string, len(matched))
+			for i, item := range matched {
+				names[i] = name(item)
+			}
+			sort.Strings(names)
+			err = &AmbiguousNameError{Kind: kind, Name: query, Candidates: names}
+			return
+		}
+	}
+
+	err = &NameNotFoundError{Kind: kind, Name: query}
+	return
+}
+
+// Lower case and collapse spaces and punctuation
+func normalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Partial names or small typos (1 edit per 5 characters)
+func fuzzyNameMatch(candidate, query string) bool {
+	if candidate == "" || query == "" {
+		return false
+	}
+
+	if strings.Contains(candidate, query) {
+		return true
+	}
+
+	maxDistance := len([]rune(query)) / 5
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	return levenshtein(candidate, query) <= maxDistance
+}
+
+// Edit distance between two strings
+func levenshtein(a, b string) int {
+	runesA, runesB := []rune(a), []rune(b)
+	previous := make([]int, len(runesB)+1)
+	current := make([]int, len(runesB)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(runesA); i++ {
+		current[0] = i
+		for j := 1; j <= len(runesB); j++ {
+			cost := 1
+			if runesA[i-1] == runesB[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(runesB)]
+}
+
+// Error returned when no entity matches a name
+type NameNotFoundError struct {
+	Kind string // 'organization', 'location', 'role' or 'policy'
+	Name string
+}
+
+func (e *NameNotFoundError) Error() string {
+	return fmt.Sprintf("no %s named '%s'", e.Kind, e.Name)
+}
+
+// Is makes errors.Is(err, ErrNameNotFound) true.
+func (e *NameNotFoundError) Is(target error) bool {
+	return target == ErrNameNotFound
+}
+
+// Error returned when several entities match a name
+type AmbiguousNameError struct {
+	Kind       string // 'organization', 'location', 'role' or 'policy'
+	Name       string
+	Candidates []string
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("ambiguous %s name '%s', candidates: %s", e.Kind, e.Name, strings.Join(e.Candidates, ", "))
+}
+
+// Is makes errors.Is(err, ErrAmbiguousName) true.
+func (e *AmbiguousNameError) Is(target error) bool {
+	return target == ErrAmbiguousName
+}
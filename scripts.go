@@ -0,0 +1,321 @@
+package ninjarmm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned by ScriptRun.Wait when the API returned no job UID and the script
+// has no UID, so its completion can't be told apart from other runs
+var ErrScriptRunNotCorrelated = errors.New("script run can't be correlated with the activity log: no job UID nor script UID")
+
+// Default delay between two activity log polls in ScriptRun.Wait
+const defaultScriptPollInterval = 5 * time.Second
+
+// Returns the scripts, built-in actions and credentials available for a device
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getDeviceScriptingOptions
+func ListDeviceScriptingOptions(deviceID int, lang string) (options ScriptingOptions, err error) {
+	values := url.Values{}
+
+	if lang != "" {
+		values.Set("lang", lang)
+	}
+
+	err = request(http.MethodGet, fmt.Sprintf("device/%d/scripting/options?%s", deviceID, values.Encode()), nil, &options)
+	return
+}
+
+// Run a script or a built-in action on a device.
+//
+// The returned ScriptRun can be used to wait for the script completion.
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/runScriptOnDevice
+//
+// Usage:
+//
+//	run, err := RunDeviceScript(ctx, deviceID, ScriptRunRequest{
+//		Type:       ScriptTypeScript,
+//		ID:         script.ID,
+//		UID:        script.UID,
+//		Parameters: "-Verbose",
+//		RunAs:      RunAsSystem,
+//	})
+//	if err != nil {
+//		panic(err)
+//	}
+//	result, err := run.Wait(ctx)
+func RunDeviceScript(ctx context.Context, deviceID int, script ScriptRunRequest) (run *ScriptRun, err error) {
+	if deviceID == 0 {
+		err = errors.New("device ID required")
+		return
+	} else if script.ID == 0 && script.UID == "" {
+		err = errors.New("script ID or UID required")
+		return
+	}
+
+	if script.Type == "" {
+		script.Type = ScriptTypeScript
+	}
+
+	// Remember the most recent activity of the device before running the
+	// script, so Wait only looks at newer activities
+	log, err := getActivityLog(ctx, ActivityLogOptions{
+		DeviceFilter: fmt.Sprintf("id = %d", deviceID),
+		PageSize:     10,
+	})
+	if err != nil {
+		err = fmt.Errorf("error getting device activity log: %w", err)
+		return
+	}
+
+	run = &ScriptRun{
+		DeviceID:  deviceID,
+		Request:   script,
+		StartedAt: time.Now(),
+	}
+
+	for _, activity := range log.Activities {
+		if activity.ID > run.lastActivityID {
+			run.lastActivityID = activity.ID
+		}
+	}
+
+	err = requestContext(ctx, http.MethodPost, fmt.Sprintf("device/%d/script/run", deviceID), script, &run.Response)
+	if err != nil {
+		run = nil
+	}
+	return
+}
+
+// Run a script or a built-in action on the device.
+//
+// See RunDeviceScript
+func (device Device) RunScript(ctx context.Context, script ScriptRunRequest) (run *ScriptRun, err error) {
+	return RunDeviceScript(ctx, device.ID, script)
+}
+
+// Wait blocks until the activity reporting the end of the script run is
+// found in the activity log, or until the context is done.
+//
+// The activity log is polled every PollInterval (5 seconds by default).
+// ErrScriptRunNotCorrelated is returned right away when neither the job UID
+// nor the script UID is known.
+func (run *ScriptRun) Wait(ctx context.Context) (result ScriptResult, err error) {
+	if run.Response.JobUID == "" && run.Request.UID == "" {
+		err = ErrScriptRunNotCorrelated
+		return
+	}
+
+	interval := run.PollInterval
+	if interval <= 0 {
+		interval = defaultScriptPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var found bool
+		result, found, err = run.poll(ctx)
+		if err != nil || found {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Look for the activity ending the script run in the activity log
+func (run *ScriptRun) poll(ctx context.Context) (result ScriptResult, found bool, err error) {
+	log, err := getActivityLog(ctx, ActivityLogOptions{
+		DeviceFilter:        fmt.Sprintf("id = %d", run.DeviceID),
+		NewerThanActivityID: run.lastActivityID,
+		PageSize:            100,
+	})
+	if err != nil {
+		err = fmt.Errorf("error getting device activity log: %w", err)
+		return
+	}
+
+	// Activities are in reverse chronological order, take the oldest match
+	for i := len(log.Activities) - 1; i >= 0; i-- {
+		activity := log.Activities[i]
+		if run.matches(activity) {
+			return newScriptResult(activity), true, nil
+		}
+	}
+
+	return
+}
+
+// Check if an activity is the completion of the script run
+func (run *ScriptRun) matches(activity Activity) bool {
+	if activity.DeviceID != run.DeviceID || activity.ID <= run.lastActivityID {
+		return false
+	}
+
+	if activity.ActivityType != ActivityTypeScripting && activity.ActivityType != ActivityTypeAction {
+		return false
+	}
+
+	switch activity.ActivityResult {
+	case ActivityResultSuccess, ActivityResultFailure, ActivityResultUnsupported:
+	default:
+		return false
+	}
+
+	switch {
+	case run.Response.JobUID != "" && activity.SeriesUID != "":
+		return activity.SeriesUID == run.Response.JobUID
+	case run.Request.UID != "" && activity.SourceConfigUID != "":
+		return activity.SourceConfigUID == run.Request.UID
+	default:
+		// Could be the completion of another run
+		return false
+	}
+}
+
+// Build the script result from the completion activity
+func newScriptResult(activity Activity) (result ScriptResult) {
+	result = ScriptResult{
+		Activity: activity,
+		Result:   activity.ActivityResult,
+		Output:   activity.Message,
+		ExitCode: -1,
+	}
+
+	data, ok := activity.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	fields := CustomFields(data)
+	if output := fields.StringField("output"); output != "" {
+		result.Output = output
+	}
+
+	for _, key := range []string{"exitCode", "resultCode"} {
+		if code, ok := exitCode(fields[key]); ok {
+			result.ExitCode = code
+			break
+		}
+	}
+
+	return
+}
+
+// Exit code reported as a JSON number or a numeric string
+func exitCode(raw interface{}) (code int, ok bool) {
+	switch value := raw.(type) {
+	case float64:
+		return int(value), true
+	case int:
+		return value, true
+	case string:
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Succeeded returns true if the script ended successfully
+func (result ScriptResult) Succeeded() bool {
+	return result.Result == ActivityResultSuccess
+}
+
+type ScriptingOptions struct {
+	Categories  []ScriptCategory  `json:"categories"`
+	Scripts     []ScriptingOption `json:"scripts"`
+	Credentials struct {
+		Roles       []string           `json:"roles"`
+		Credentials []ScriptCredential `json:"credentials"`
+	} `json:"credentials"`
+}
+
+type ScriptCategory struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Internal bool   `json:"internal"`
+}
+
+type ScriptingOption struct {
+	Type         ScriptType `json:"type"` // 'ACTION' or 'SCRIPT'
+	ID           int        `json:"id"`
+	UID          string     `json:"uid"`
+	Name         string     `json:"name"`
+	Language     string     `json:"language"`
+	Description  string     `json:"description"`
+	Architecture []string   `json:"architecture"`
+	CategoryID   int        `json:"categoryId"`
+}
+
+type ScriptCredential struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ScriptRunRequest struct {
+	Type       ScriptType `json:"type"`                 // 'ACTION' or 'SCRIPT' (default: 'SCRIPT')
+	ID         int        `json:"id,omitempty"`         // Script identifier
+	UID        string     `json:"uid,omitempty"`        // Built-in action identifier
+	Parameters string     `json:"parameters,omitempty"` // Script parameters
+	RunAs      string     `json:"runAs,omitempty"`      // One of the RunAs constants or a credential ID
+}
+
+// Response returned (if any) by the API when running a script
+type ScriptRunResponse struct {
+	JobUID     string `json:"jobUid,omitempty"`
+	ActivityID int    `json:"activityId,omitempty"`
+}
+
+// Handle on a script running on a device
+type ScriptRun struct {
+	DeviceID     int
+	Request      ScriptRunRequest
+	Response     ScriptRunResponse
+	StartedAt    time.Time
+	PollInterval time.Duration // Delay between two activity log polls (default: 5 seconds)
+
+	// Internal fields
+	lastActivityID int
+}
+
+// Result of a script run
+type ScriptResult struct {
+	Activity Activity       // Activity reporting the script completion
+	Result   ActivityResult // 'SUCCESS', 'FAILURE' or 'UNSUPPORTED'
+	ExitCode int            // -1 when not reported
+	Output   string
+}
+
+type ScriptType string
+
+const (
+	ScriptTypeScript ScriptType = "SCRIPT"
+	ScriptTypeAction ScriptType = "ACTION"
+)
+
+// Common values for ScriptRunRequest.RunAs, a credential ID can also be used
+const (
+	RunAsSystem                = "system"
+	RunAsLoggedOnUser          = "loggedonuser"
+	RunAsLocalAdmin            = "LOCAL_ADMIN"
+	RunAsDomainAdmin           = "DOMAIN_ADMIN"
+	RunAsPreferredWindowsAdmin = "PREFERRED_WINDOWS_ADMIN"
+	RunAsMacScript             = "SR_MAC_SCRIPT"
+	RunAsLinuxScript           = "SR_LINUX_SCRIPT"
+)
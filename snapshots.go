@@ -0,0 +1,471 @@
+package ninjarmm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Version of the snapshot archive format written by this package. Version 2
+// added the role and policy names.
+const SnapshotVersion = 2
+
+// Export organizations (all of them if no ID is given) with their settings,
+// policy mappings, custom fields, locations and documents. The names of the
+// roles and policies of the policy mappings are recorded to translate their
+// IDs on import.
+//
+// Organizations can't be deleted with the API, write the archive somewhere
+// safe before risky changes.
+//
+// Usage:
+//
+//	archive, err := ninjarmm.ExportOrganizations()
+//	...
+//	file, _ := os.Create("backup.json")
+//	defer file.Close()
+//	err = archive.Write(file)
+func ExportOrganizations(organizationIDs ...int) (archive SnapshotArchive, err error) {
+	archive = SnapshotArchive{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if len(organizationIDs) == 0 {
+		var organizations []Organization
+		organizations, err = ListOrganizations()
+		if err != nil {
+			err = fmt.Errorf("error listing organizations: %w", err)
+			return
+		}
+
+		for _, organization := range organizations {
+			organizationIDs = append(organizationIDs, organization.ID)
+		}
+	}
+
+	for _, organizationID := range organizationIDs {
+		var snapshot OrganizationSnapshot
+		snapshot, err = ExportOrganization(organizationID)
+		if err != nil {
+			return
+		}
+		archive.Organizations = append(archive.Organizations, snapshot)
+	}
+
+	roleIDs, policyIDs := archive.policyMappingIDs()
+	if len(roleIDs) == 0 {
+		return
+	}
+
+	roles, err := ListDeviceRoles()
+	if err != nil {
+		err = fmt.Errorf("error listing roles: %w", err)
+		return
+	}
+
+	policies, err := ListDevicePolicies()
+	if err != nil {
+		err = fmt.Errorf("error listing policies: %w", err)
+		return
+	}
+
+	archive.Roles = make(map[int]string, len(roleIDs))
+	for _, role := range roles {
+		if roleIDs[role.ID] {
+			archive.Roles[role.ID] = role.Name
+		}
+	}
+
+	archive.Policies = make(map[int]string, len(policyIDs))
+	for _, policy := range policies {
+		if policyIDs[policy.ID] {
+			archive.Policies[policy.ID] = policy.Name
+		}
+	}
+
+	return
+}
+
+// Role and policy IDs used by the policy mappings of the archive
+func (archive SnapshotArchive) policyMappingIDs() (roleIDs, policyIDs map[int]bool) {
+	roleIDs, policyIDs = make(map[int]bool), make(map[int]bool)
+
+	for _, snapshot := range archive.Organizations {
+		for _, item := range snapshot.Organization.Policies {
+			roleIDs[item.NodeRoleID] = true
+			policyIDs[item.PolicyID] = true
+		}
+	}
+	return
+}
+
+// Export an organization with its settings, policy mappings, custom fields,
+// locations and documents.
+func ExportOrganization(organizationID int) (snapshot OrganizationSnapshot, err error) {
+	snapshot.Organization, err = GetOrganization(organizationID)
+	if err != nil {
+		err = fmt.Errorf("error getting organization %d: %w", organizationID, err)
+		return
+	}
+
+	// Locations are exported with their custom fields below
+	snapshot.Organization.Locations = nil
+
+	snapshot.CustomFields, err = GetOrganizationCustomFields(organizationID)
+	if err != nil {
+		err = fmt.Errorf("error getting organization %d custom fields: %w", organizationID, err)
+		return
+	}
+
+	locations, err := ListOrganizationLocations(organizationID)
+	if err != nil {
+		err = fmt.Errorf("error listing organization %d locations: %w", organizationID, err)
+		return
+	}
+
+	for _, location := range locations {
+		locationSnapshot := LocationSnapshot{Location: location}
+
+		locationSnapshot.CustomFields, err = GetLocationCustomFields(organizationID, location.ID)
+		if err != nil {
+			err = fmt.Errorf("error getting location %d custom fields: %w", location.ID, err)
+			return
+		}
+
+		snapshot.Locations = append(snapshot.Locations, locationSnapshot)
+	}
+
+	snapshot.Documents, err = GetOrganizationDocuments(organizationID)
+	if err != nil {
+		err = fmt.Errorf("error getting organization %d documents: %w", organizationID, err)
+		return
+	}
+
+	return
+}
+
+// Write the archive as indented JSON.
+func (archive SnapshotArchive) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(archive)
+}
+
+// Read an archive written by SnapshotArchive.Write. Archives written by a
+// newer version of the package are rejected.
+func ReadSnapshotArchive(r io.Reader) (archive SnapshotArchive, err error) {
+	err = json.NewDecoder(r).Decode(&archive)
+	if err != nil {
+		err = fmt.Errorf("error decoding snapshot archive: %w", err)
+		return
+	}
+
+	if archive.Version < 1 || archive.Version > SnapshotVersion {
+		err = fmt.Errorf("unsupported snapshot archive version %d", archive.Version)
+	}
+	return
+}
+
+// Restore the organizations of an archive, in the same or another tenant.
+//
+// Organizations and locations are matched by name: missing ones are created,
+// existing ones are updated. Custom fields, policy mappings and existing
+// documents (matched by name) are restored.
+//
+// Role and policy IDs are translated with `options`, otherwise by the names
+// recorded in the archive (exact match). If some IDs can't be translated
+// nothing is imported and the error lists them.
+//
+// Documents missing from the target can't be created (the API requires
+// their template), they are reported in SnapshotImport.Warnings.
+//
+// Import stops at the first error, the returned SnapshotImport holds the
+// IDs mapped so far. Enable the dry-run mode (see EnableDryRun) to review
+// the requests first.
+func ImportOrganizations(archive SnapshotArchive, options SnapshotImportOptions) (result SnapshotImport, err error) {
+	result = SnapshotImport{
+		Organizations: make(map[int]int),
+		Locations:     make(map[int]int),
+	}
+
+	if roleIDs, _ := archive.policyMappingIDs(); len(roleIDs) > 0 {
+		var roles []DeviceRole
+		roles, err = ListDeviceRoles()
+		if err != nil {
+			err = fmt.Errorf("error listing roles: %w", err)
+			return
+		}
+
+		var policies []Policy
+		policies, err = ListDevicePolicies()
+		if err != nil {
+			err = fmt.Errorf("error listing policies: %w", err)
+			return
+		}
+
+		options, err = archive.resolveIDs(options, roles, policies)
+		if err != nil {
+			return
+		}
+	}
+
+	organizations, err := ListOrganizations()
+	if err != nil {
+		err = fmt.Errorf("error listing organizations: %w", err)
+		return
+	}
+
+	existing := make(map[string]int, len(organizations))
+	for _, organization := range organizations {
+		existing[organization.Name] = organization.ID
+	}
+
+	for _, snapshot := range archive.Organizations {
+		err = result.importOrganization(snapshot, existing, options)
+		if err != nil {
+			err = fmt.Errorf("error importing organization '%s': %w", snapshot.Organization.Name, err)
+			return
+		}
+	}
+
+	return
+}
+
+func (result *SnapshotImport) importOrganization(snapshot OrganizationSnapshot, existing map[string]int, options SnapshotImportOptions) (err error) {
+	source := snapshot.Organization
+	organizationID, found := existing[source.Name]
+
+	if found {
+		err = UpdateOrganization(Organization{
+			ID:               organizationID,
+			Description:      source.Description,
+			NodeApprovalMode: source.NodeApprovalMode,
+		})
+		if err != nil {
+			return
+		}
+
+		if len(source.Settings) > 0 {
+			err = UpdateOrganizationSettings(organizationID, source.Settings)
+			if err != nil {
+				return
+			}
+		}
+	} else {
+		var created OrganizationDetailed
+		created, err = CreateOrganization(OrganizationDetailed{
+			Name:             source.Name,
+			Description:      source.Description,
+			NodeApprovalMode: source.NodeApprovalMode,
+			Settings:         source.Settings,
+		}, 0)
+		if err != nil {
+			return
+		}
+		organizationID = created.ID
+	}
+
+	result.Organizations[source.ID] = organizationID
+
+	if len(snapshot.CustomFields) > 0 {
+		err = SetOrganizationCustomFields(organizationID, snapshot.CustomFields)
+		if err != nil {
+			return
+		}
+	}
+
+	err = result.importLocations(organizationID, snapshot.Locations)
+	if err != nil {
+		return
+	}
+
+	if len(source.Policies) > 0 {
+		policies := make([]OrganizationPolicyItem, len(source.Policies))
+		for i, item := range source.Policies {
+			policies[i] = OrganizationPolicyItem{
+				NodeRoleID: options.role(item.NodeRoleID),
+				PolicyID:   options.policy(item.PolicyID),
+			}
+		}
+
+		_, err = UpdateOrganizationPolicies(organizationID, policies)
+		if err != nil {
+			return
+		}
+	}
+
+	return result.importDocuments(organizationID, source.Name, snapshot.Documents)
+}
+
+func (result *SnapshotImport) importLocations(organizationID int, snapshots []LocationSnapshot) (err error) {
+	locations, err := ListOrganizationLocations(organizationID)
+	if err != nil {
+		return
+	}
+
+	existing := make(map[string]int, len(locations))
+	for _, location := range locations {
+		existing[location.Name] = location.ID
+	}
+
+	for _, snapshot := range snapshots {
+		source := snapshot.Location
+		location := Location{
+			Name:        source.Name,
+			Address:     source.Address,
+			Description: source.Description,
+		}
+
+		locationID, found := existing[source.Name]
+		if found {
+			location.Name = ""
+			err = UpdateLocation(organizationID, locationID, location)
+		} else {
+			var created Location
+			created, err = CreateLocation(organizationID, location)
+			locationID = created.ID
+		}
+		if err != nil {
+			return fmt.Errorf("error restoring location '%s': %w", source.Name, err)
+		}
+
+		result.Locations[source.ID] = locationID
+
+		if len(snapshot.CustomFields) > 0 {
+			err = SetLocationCustomFields(organizationID, locationID, snapshot.CustomFields)
+			if err != nil {
+				return fmt.Errorf("error restoring location '%s' custom fields: %w", source.Name, err)
+			}
+		}
+	}
+
+	return
+}
+
+func (result *SnapshotImport) importDocuments(organizationID int, organizationName string, documents []Document) (err error) {
+	if len(documents) == 0 {
+		return
+	}
+
+	current, err := GetOrganizationDocuments(organizationID)
+	if err != nil {
+		return
+	}
+
+	existing := make(map[string]int, len(current))
+	for _, document := range current {
+		existing[document.ClientDocumentName] = document.ClientDocumentID
+	}
+
+	for _, document := range documents {
+		documentID, found := existing[document.ClientDocumentName]
+		if !found {
+			result.warnf("document '%s' of organization '%s' not restored, it must be created from its template first", document.ClientDocumentName, organizationName)
+			continue
+		}
+
+		document.ClientDocumentID = documentID
+		err = UpdateOrganizationDocument(organizationID, document)
+		if err != nil {
+			return fmt.Errorf("error restoring document '%s': %w", document.ClientDocumentName, err)
+		}
+	}
+
+	return
+}
+
+func (result *SnapshotImport) warnf(format string, args ...interface{}) {
+	result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Complete the ID mappings of `options` with the target roles and policies
+// named as in the archive. IDs neither mapped nor named (or whose name
+// matches none or several targets) are returned in the error.
+func (archive SnapshotArchive) resolveIDs(options SnapshotImportOptions, roles []DeviceRole, policies []Policy) (resolved SnapshotImportOptions, err error) {
+	roleIDs, policyIDs := archive.policyMappingIDs()
+
+	roleNames := make(map[string][]int, len(roles))
+	for _, role := range roles {
+		roleNames[role.Name] = append(roleNames[role.Name], role.ID)
+	}
+
+	policyNames := make(map[string][]int, len(policies))
+	for _, policy := range policies {
+		policyNames[policy.Name] = append(policyNames[policy.Name], policy.ID)
+	}
+
+	resolved.RoleIDs, resolved.PolicyIDs = make(map[int]int), make(map[int]int)
+	unmappedRoles := resolveSnapshotIDs(roleIDs, options.RoleIDs, archive.Roles, roleNames, resolved.RoleIDs)
+	unmappedPolicies := resolveSnapshotIDs(policyIDs, options.PolicyIDs, archive.Policies, policyNames, resolved.PolicyIDs)
+
+	if len(unmappedRoles) > 0 || len(unmappedPolicies) > 0 {
+		err = fmt.Errorf("unmapped role IDs %v and policy IDs %v, map them with SnapshotImportOptions", unmappedRoles, unmappedPolicies)
+	}
+	return
+}
+
+// Map each source ID explicitly or by name, returns the IDs left unmapped
+func resolveSnapshotIDs(ids map[int]bool, explicit map[int]int, names map[int]string, targets map[string][]int, resolved map[int]int) (unmapped []int) {
+	for id := range ids {
+		if mapped, ok := explicit[id]; ok {
+			resolved[id] = mapped
+		} else if name, ok := names[id]; ok && len(targets[name]) == 1 {
+			resolved[id] = targets[name][0]
+		} else {
+			unmapped = append(unmapped, id)
+		}
+	}
+
+	sort.Ints(unmapped)
+	return
+}
+
+func (options SnapshotImportOptions) role(roleID int) int {
+	return options.RoleIDs[roleID]
+}
+
+func (options SnapshotImportOptions) policy(policyID int) int {
+	return options.PolicyIDs[policyID]
+}
+
+// Versioned archive of organizations, see ExportOrganizations
+type SnapshotArchive struct {
+	Version       int                    `json:"version"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	Organizations []OrganizationSnapshot `json:"organizations"`
+	Roles         map[int]string         `json:"roles,omitempty"`    // Names of the roles of the policy mappings by ID
+	Policies      map[int]string         `json:"policies,omitempty"` // Names of the policies of the policy mappings by ID
+}
+
+type OrganizationSnapshot struct {
+	Organization OrganizationDetailed `json:"organization"` // Settings and policy mappings included
+	CustomFields CustomFields         `json:"customFields,omitempty"`
+	Locations    []LocationSnapshot   `json:"locations,omitempty"`
+	Documents    []Document           `json:"documents,omitempty"`
+}
+
+type LocationSnapshot struct {
+	Location     Location     `json:"location"`
+	CustomFields CustomFields `json:"customFields,omitempty"`
+}
+
+// Options for ImportOrganizations
+type SnapshotImportOptions struct {
+	// Source role ID → target role ID, takes precedence over the names
+	// recorded in the archive
+	RoleIDs map[int]int
+
+	// Source policy ID → target policy ID, takes precedence over the names
+	// recorded in the archive
+	PolicyIDs map[int]int
+}
+
+// Result of ImportOrganizations
+type SnapshotImport struct {
+	Organizations map[int]int // Source organization ID → target organization ID
+	Locations     map[int]int // Source location ID → target location ID
+	Warnings      []string    // What could not be restored
+}
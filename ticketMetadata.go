@@ -0,0 +1,297 @@
+package ninjarmm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// Ticket forms, statuses and attributes, see GetTicketMetadata
+	ticketMetadata   *TicketMetadata
+	ticketMetadataMu sync.Mutex
+)
+
+// List ticket forms
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getTicketForms
+func ListTicketForms() (forms []TicketForm, err error) {
+	err = request(http.MethodGet, "ticketing/ticket-form", nil, &forms)
+	return
+}
+
+// List ticket statuses
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getStatuses
+func ListTicketStatuses() (statuses []TicketStatus, err error) {
+	err = request(http.MethodGet, "ticketing/statuses", nil, &statuses)
+	return
+}
+
+// List ticket attribute definitions
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getTicketAttributes
+func ListTicketAttributes() (attributes []TicketAttributeDefinition, err error) {
+	err = request(http.MethodGet, "ticketing/attributes", nil, &attributes)
+	return
+}
+
+// Returns the ticket forms, statuses and attribute definitions. They are
+// loaded on first use and kept until ClearTicketMetadata.
+func GetTicketMetadata() (metadata TicketMetadata, err error) {
+	ticketMetadataMu.Lock()
+	defer ticketMetadataMu.Unlock()
+
+	if ticketMetadata != nil {
+		return *ticketMetadata, nil
+	}
+
+	metadata.Forms, err = ListTicketForms()
+	if err != nil {
+		err = fmt.Errorf("error listing ticket forms: %w", err)
+		return
+	}
+
+	metadata.Statuses, err = ListTicketStatuses()
+	if err != nil {
+		err = fmt.Errorf("error listing ticket statuses: %w", err)
+		return
+	}
+
+	metadata.Attributes, err = ListTicketAttributes()
+	if err != nil {
+		err = fmt.Errorf("error listing ticket attributes: %w", err)
+		return
+	}
+
+	ticketMetadata = &metadata
+	return
+}
+
+// Empty the ticket metadata cache, use it after ticket forms, statuses or
+// attributes were changed.
+func ClearTicketMetadata() {
+	ticketMetadataMu.Lock()
+	defer ticketMetadataMu.Unlock()
+	ticketMetadata = nil
+}
+
+// Returns the value of a ticket attribute by name (case insensitive), nil if
+// the ticket has no value. Dropdown values are returned as their label, see
+// CustomFieldDefinition.Decode.
+func (ticket Ticket) Attribute(name string) (value any, err error) {
+	metadata, err := GetTicketMetadata()
+	if err != nil {
+		return
+	}
+	return metadata.attribute(ticket, name)
+}
+
+// Set the value of a ticket attribute by name (case insensitive). Dropdown
+// values can be given by label.
+func (ticket *Ticket) SetAttribute(name string, value any) (err error) {
+	metadata, err := GetTicketMetadata()
+	if err != nil {
+		return
+	}
+	return metadata.setAttribute(ticket, name, value)
+}
+
+// Check the ticket before Create: required fields, known form, allowed
+// status and type, known attributes with valid values and required
+// attributes of the form.
+func (newTicket NewTicket) Validate() (err error) {
+	metadata, err := GetTicketMetadata()
+	if err != nil {
+		return
+	}
+	return metadata.Validate(newTicket)
+}
+
+func (metadata TicketMetadata) attribute(ticket Ticket, name string) (value any, err error) {
+	definition, ok := metadata.AttributeByName(name)
+	if !ok {
+		err = fmt.Errorf("unknown ticket attribute '%s'", name)
+		return
+	}
+
+	for _, attribute := range ticket.AttributeValues {
+		if attribute.AttributeID == definition.ID {
+			return definition.customField().Decode(attribute.Value)
+		}
+	}
+
+	return
+}
+
+func (metadata TicketMetadata) setAttribute(ticket *Ticket, name string, value any) (err error) {
+	definition, ok := metadata.AttributeByName(name)
+	if !ok {
+		return fmt.Errorf("unknown ticket attribute '%s'", name)
+	}
+
+	raw, err := definition.customField().Encode(value)
+	if err != nil {
+		return
+	}
+
+	for i, attribute := range ticket.AttributeValues {
+		if attribute.AttributeID == definition.ID {
+			ticket.AttributeValues[i].Value = raw
+			return
+		}
+	}
+
+	ticket.AttributeValues = append(ticket.AttributeValues, TicketAttributes{AttributeID: definition.ID, Value: raw})
+	return
+}
+
+// Validate checks a new ticket against the metadata, all problems are
+// returned joined.
+func (metadata TicketMetadata) Validate(newTicket NewTicket) error {
+	var errs []error
+
+	if newTicket.ClientID == 0 {
+		errs = append(errs, errors.New("client ID required"))
+	}
+
+	if newTicket.Subject == "" {
+		errs = append(errs, errors.New("subject required"))
+	} else if len([]rune(newTicket.Subject)) > 200 {
+		errs = append(errs, errors.New("subject longer than 200 characters"))
+	}
+
+	switch newTicket.Type {
+	case TicketTypeNone, TicketTypeProblem, TicketTypeQuestion, TicketTypeIncident, TicketTypeTask:
+	default:
+		errs = append(errs, fmt.Errorf("invalid ticket type '%s'", newTicket.Type))
+	}
+
+	if newTicket.Status == "" {
+		errs = append(errs, errors.New("status required"))
+	} else if _, ok := metadata.StatusByName(newTicket.Status); !ok {
+		errs = append(errs, fmt.Errorf("unknown ticket status '%s'", newTicket.Status))
+	}
+
+	var form TicketForm
+	if newTicket.TicketFormID == 0 {
+		errs = append(errs, errors.New("ticket form ID required"))
+	} else if f, ok := metadata.Form(newTicket.TicketFormID); !ok {
+		errs = append(errs, fmt.Errorf("unknown ticket form %d", newTicket.TicketFormID))
+	} else {
+		form = f
+	}
+
+	values := make(map[int]any, len(newTicket.Attributes))
+	for _, attribute := range newTicket.Attributes {
+		definition, ok := metadata.attributeByID(attribute.AttributeID)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown ticket attribute %d", attribute.AttributeID))
+			continue
+		}
+
+		if _, err := definition.customField().Encode(attribute.Value); err != nil {
+			errs = append(errs, err)
+		}
+		values[attribute.AttributeID] = attribute.Value
+	}
+
+	for _, field := range form.Fields {
+		if field.Required && values[field.ID] == nil {
+			name := field.Name
+			if definition, ok := metadata.attributeByID(field.ID); ok {
+				name = definition.Name
+			}
+			errs = append(errs, fmt.Errorf("ticket attribute '%s' required by form '%s'", name, form.Name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Form returns a ticket form by ID.
+func (metadata TicketMetadata) Form(formID int) (form TicketForm, ok bool) {
+	for _, form := range metadata.Forms {
+		if form.ID == formID {
+			return form, true
+		}
+	}
+	return
+}
+
+// StatusByName returns a ticket status by name, display name (case
+// insensitive) or status ID.
+func (metadata TicketMetadata) StatusByName(name string) (status TicketStatus, ok bool) {
+	statusID, _ := strconv.Atoi(name)
+
+	for _, status := range metadata.Statuses {
+		if status.Name == name || strings.EqualFold(status.DisplayName, name) || (statusID != 0 && status.StatusID == statusID) {
+			return status, true
+		}
+	}
+	return
+}
+
+// AttributeByName returns a ticket attribute definition by name (case
+// insensitive).
+func (metadata TicketMetadata) AttributeByName(name string) (definition TicketAttributeDefinition, ok bool) {
+	for _, definition := range metadata.Attributes {
+		if strings.EqualFold(definition.Name, name) {
+			return definition, true
+		}
+	}
+	return
+}
+
+func (metadata TicketMetadata) attributeByID(attributeID int) (definition TicketAttributeDefinition, ok bool) {
+	for _, definition := range metadata.Attributes {
+		if definition.ID == attributeID {
+			return definition, true
+		}
+	}
+	return
+}
+
+// Ticket attributes share the value formats of custom fields
+func (definition TicketAttributeDefinition) customField() CustomFieldDefinition {
+	return CustomFieldDefinition{
+		ID:          definition.ID,
+		Name:        definition.Name,
+		Description: definition.Description,
+		Type:        definition.AttributeType,
+		Content:     definition.Content,
+	}
+}
+
+// Ticket forms, statuses and attribute definitions, see GetTicketMetadata
+type TicketMetadata struct {
+	Forms      []TicketForm
+	Statuses   []TicketStatus
+	Attributes []TicketAttributeDefinition
+}
+
+type TicketForm struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Active      bool              `json:"active"`
+	Fields      []TicketFormField `json:"fields"`
+}
+
+type TicketFormField struct {
+	ID       int    `json:"id"` // Attribute ID
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+}
+
+type TicketAttributeDefinition struct {
+	ID            int                `json:"id"`
+	Name          string             `json:"name"`
+	Description   string             `json:"description"`
+	AttributeType CustomFieldType    `json:"attributeType"`
+	Content       CustomFieldContent `json:"content"`
+	Active        bool               `json:"active"`
+}
@@ -0,0 +1,191 @@
+package ninjarmm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number of fetch-modify-write attempts made by UpdateTicketFunc when the
+// ticket was changed concurrently
+const ticketUpdateAttempts = 3
+
+// Returned (wrapped in a *TicketConflictError) when a ticket was changed by
+// someone else since it was read
+var ErrTicketConflict = errors.New("ticket changed concurrently")
+
+// Fetch-modify-write a ticket with optimistic concurrency.
+//
+// `modify` receives a fresh copy of the ticket, only the fields it changes
+// are sent along with the required fields and the version read (see
+// UpdateTicketPartial). If the ticket was changed in the
+// meantime the update is retried with a fresh copy, and after 3 attempts a
+// *TicketConflictError is returned. Returning an error from `modify` aborts
+// the update.
+//
+// Usage:
+//
+//	ticket, err := ninjarmm.UpdateTicketFunc(ctx, ticketID, func(ticket *ninjarmm.Ticket) error {
+//		ticket.Tags = append(ticket.Tags, "escalated")
+//		return nil
+//	})
+func UpdateTicketFunc(ctx context.Context, ticketID int, modify func(ticket *Ticket) error) (updatedTicket Ticket, err error) {
+	if ticketID == 0 {
+		err = errors.New("ticket ID required")
+		return
+	}
+
+	for attempt := 0; attempt < ticketUpdateAttempts; attempt++ {
+		var original Ticket
+		err = requestContext(ctx, http.MethodGet, fmt.Sprintf("ticketing/ticket/%d", ticketID), nil, &original)
+		if err != nil {
+			return
+		}
+
+		modified := original
+		modified.Tags = append([]string(nil), original.Tags...)
+		modified.CcList.Uids = append([]string(nil), original.CcList.Uids...)
+		modified.CcList.Emails = append([]string(nil), original.CcList.Emails...)
+		modified.AttributeValues = append([]TicketAttributes(nil), original.AttributeValues...)
+
+		if err = modify(&modified); err != nil {
+			return
+		}
+
+		updatedTicket, err = UpdateTicketPartial(ctx, original, modified)
+		if !errors.Is(err, ErrTicketConflict) {
+			return
+		}
+	}
+
+	return
+}
+
+// Send only the fields changed between `original` and `modified`, with the
+// version of `original`. Fields not changed are not cleared, fields set to
+// a zero value (0, "", false) are sent as such and cleared lists as empty
+// lists. The fields required by the API (clientId, ticketFormId, subject
+// and status) are always sent. Fields are sent in the format of the write
+// API (see NewTicket): the status as its status ID, the CC list as "cc" and
+// the attribute values as "attributes".
+//
+// The API rejects the update with 409 Conflict (or 412) when the ticket
+// version is no longer `original.Version`, a *TicketConflictError is then
+// returned.
+func UpdateTicketPartial(ctx context.Context, original, modified Ticket) (updatedTicket Ticket, err error) {
+	if original.ID == 0 {
+		err = errors.New("ticket ID required")
+		return
+	}
+
+	changes := ticketChanges(original, modified)
+	if len(changes) == 0 {
+		return original, nil
+	}
+
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	payload := make(map[string]any, len(changes)+len(ticketRequiredFields)+1)
+	for field, value := range changes {
+		payload[field] = value
+	}
+
+	all := ticketWriteFields(modified)
+	for _, field := range ticketRequiredFields {
+		payload[field] = all[field]
+	}
+	payload["version"] = original.Version
+
+	err = requestContext(ctx, http.MethodPut, fmt.Sprintf("ticketing/ticket/%d", original.ID), payload, &updatedTicket)
+
+	var apiError *APIError
+	if errors.As(err, &apiError) && (apiError.StatusCode == http.StatusConflict || apiError.StatusCode == http.StatusPreconditionFailed) {
+		err = &TicketConflictError{TicketID: original.ID, Version: original.Version, Fields: fields, Err: apiError}
+	}
+	return
+}
+
+// Fields always sent by UpdateTicketPartial, required by the API
+var ticketRequiredFields = []string{"clientId", "ticketFormId", "subject", "status"}
+
+// Read only ticket fields, never sent
+var ticketReadOnlyFields = map[string]bool{"id": true, "version": true, "createTime": true}
+
+// Write fields changed between two tickets, compared on the typed values so
+// that a field set to its zero value is a change
+func ticketChanges(original, modified Ticket) (changes map[string]any) {
+	before, after := ticketWriteFields(original), ticketWriteFields(modified)
+	changes = make(map[string]any)
+
+	for field, value := range after {
+		if !ticketReadOnlyFields[field] && !reflect.DeepEqual(before[field], value) {
+			changes[field] = value
+		}
+	}
+	return
+}
+
+// Ticket fields renamed or reformatted by the write API
+var ticketWriteNames = map[string]string{
+	"ccList":          "cc",
+	"attributeValues": "attributes",
+}
+
+// Ticket field values by write API name, nil slices as empty slices so that
+// cleared lists are sent as such
+func ticketWriteFields(ticket Ticket) (fields map[string]any) {
+	value := reflect.ValueOf(ticket)
+	fields = make(map[string]any, value.NumField())
+
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if writeName, ok := ticketWriteNames[name]; ok {
+			name = writeName
+		}
+
+		field := value.Field(i)
+		if field.Kind() == reflect.Slice && field.IsNil() {
+			field = reflect.MakeSlice(field.Type(), 0, 0)
+		}
+		fields[name] = field.Interface()
+	}
+
+	// The write API takes the status ID, as NewTicket.Status
+	fields["status"] = strconv.Itoa(ticket.Status.StatusID)
+	return
+}
+
+// Error returned when a ticket was changed concurrently
+type TicketConflictError struct {
+	TicketID int
+	Version  int      // Version the update was based on
+	Fields   []string // Fields changed by the update
+	Err      error    // Error returned by the API
+}
+
+func (e *TicketConflictError) Error() string {
+	return fmt.Sprintf("ticket %d changed concurrently since version %d", e.TicketID, e.Version)
+}
+
+// Is makes errors.Is(err, ErrTicketConflict) true.
+func (e *TicketConflictError) Is(target error) bool {
+	return target == ErrTicketConflict
+}
+
+// Unwrap returns the API error.
+func (e *TicketConflictError) Unwrap() error {
+	return e.Err
+}
@@ -40,7 +40,8 @@ func GetTicket(ticketID int) (ticket Ticket, err error) {
 	return
 }
 
-// Change ticket fields. Does not accept comments or files
+// Change ticket fields. Does not accept comments or files. The whole ticket
+// is sent, use UpdateTicketFunc to avoid overwriting concurrent changes
 //
 // See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/update
 func (ticket Ticket) Update() (updatedTicket Ticket, err error) {
@@ -270,6 +271,7 @@ type TicketingBoard struct {
 	TicketCount int             `json:"ticketCount"`
 }
 
+// Board condition or filter, see NewBoardQuery for a typed builder
 type BoardCondition struct {
 	Field    string `json:"field"`
 	Operator string `json:"operator"`
@@ -295,11 +297,8 @@ type SortBy struct {
 	Direction string `json:"direction"` // 'ASC' or 'DESC'
 }
 
-type Filters struct {
-	Field    string `json:"field"`
-	Operator string `json:"operator"`
-	Value    string `json:"value"`
-}
+// Deprecated: use BoardCondition, or NewBoardQuery to build filters
+type Filters = BoardCondition
 
 type BoardTickets struct {
 	Data     []BoardTicket `json:"data"`
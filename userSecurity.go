@@ -0,0 +1,221 @@
+package ninjarmm
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Points removed from the score of a user (out of 100) for each finding
+var userSecurityPenalties = map[UserSecurityFinding]int{
+	UserFindingNoMFA:               40,
+	UserFindingAdministrator:       10,
+	UserFindingPermitAllClients:    10,
+	UserFindingInvitationPending:   10,
+	UserFindingInvitationExpired:   20,
+	UserFindingDisabledWithDevices: 20,
+}
+
+// Audit the security posture of all users (technicians and end users).
+//
+// Usage:
+//
+//	report, err := ninjarmm.GetUserSecurityReport()
+//	if err != nil {
+//		panic(err)
+//	}
+//	fmt.Printf("score: %d/100\n", report.Score)
+//	report.WriteCSV(os.Stdout)
+func GetUserSecurityReport() (report UserSecurityReport, err error) {
+	users, err := ListUsers("")
+	if err != nil {
+		err = fmt.Errorf("error listing users: %w", err)
+		return
+	}
+
+	return BuildUserSecurityReport(users), nil
+}
+
+// BuildUserSecurityReport flags risky users and groups them per
+// organization. Technicians are in the organization 0.
+func BuildUserSecurityReport(users []User) (report UserSecurityReport) {
+	report.Findings = make(map[UserSecurityFinding]int)
+	organizations := make(map[int]*OrganizationUserSecurity)
+
+	totalScore := 0
+
+	for _, user := range users {
+		entry := UserSecurityEntry{User: user, Findings: userSecurityFindings(user), Score: 100}
+
+		for _, finding := range entry.Findings {
+			entry.Score -= userSecurityPenalties[finding]
+			report.Findings[finding]++
+		}
+		entry.Score = max(entry.Score, 0)
+		totalScore += entry.Score
+
+		organizationID := user.OrganizationID
+		if user.UserType == UserTypeTechnician {
+			organizationID = 0
+		}
+
+		organization, ok := organizations[organizationID]
+		if !ok {
+			organization = &OrganizationUserSecurity{OrganizationID: organizationID}
+			organizations[organizationID] = organization
+		}
+		organization.Users = append(organization.Users, entry)
+	}
+
+	report.Score = 100
+	if len(users) > 0 {
+		report.Score = totalScore / len(users)
+	}
+
+	for _, organization := range organizations {
+		organizationScore := 0
+		for _, entry := range organization.Users {
+			organizationScore += entry.Score
+		}
+		organization.Score = organizationScore / len(organization.Users)
+
+		sort.SliceStable(organization.Users, func(i, j int) bool {
+			a, b := organization.Users[i], organization.Users[j]
+			if a.Score != b.Score {
+				return a.Score < b.Score
+			}
+			return a.User.ID < b.User.ID
+		})
+
+		report.Organizations = append(report.Organizations, *organization)
+	}
+
+	sort.Slice(report.Organizations, func(i, j int) bool {
+		return report.Organizations[i].OrganizationID < report.Organizations[j].OrganizationID
+	})
+
+	return
+}
+
+// Risky settings of a user
+func userSecurityFindings(user User) (findings []UserSecurityFinding) {
+	// Disabled users are flagged too, they can be enabled again
+	if !user.MFAConfigured {
+		findings = append(findings, UserFindingNoMFA)
+	}
+
+	if user.Administrator {
+		findings = append(findings, UserFindingAdministrator)
+	}
+
+	if user.PermitAllClients {
+		findings = append(findings, UserFindingPermitAllClients)
+	}
+
+	switch user.InvitationStatus {
+	case InvitationStatusPending:
+		findings = append(findings, UserFindingInvitationPending)
+	case InvitationStatusExpired:
+		findings = append(findings, UserFindingInvitationExpired)
+	}
+
+	if !user.Enabled && len(user.DeviceIDs) > 0 {
+		findings = append(findings, UserFindingDisabledWithDevices)
+	}
+
+	return
+}
+
+// Flagged returns the users with at least one finding, worst score first.
+func (report UserSecurityReport) Flagged() (entries []UserSecurityEntry) {
+	for _, organization := range report.Organizations {
+		for _, entry := range organization.Users {
+			if len(entry.Findings) > 0 {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score < entries[j].Score
+		}
+		return entries[i].User.ID < entries[j].User.ID
+	})
+	return
+}
+
+// WriteCSV writes one line per user with a header line. Findings are
+// separated by '|'.
+func (report UserSecurityReport) WriteCSV(w io.Writer) (err error) {
+	writer := csv.NewWriter(w)
+
+	err = writer.Write([]string{"organization_id", "user_id", "email", "user_type", "score", "findings"})
+	if err != nil {
+		return
+	}
+
+	for _, organization := range report.Organizations {
+		for _, entry := range organization.Users {
+			findings := make([]string, len(entry.Findings))
+			for i, finding := range entry.Findings {
+				findings[i] = string(finding)
+			}
+
+			err = writer.Write([]string{
+				fmt.Sprint(organization.OrganizationID),
+				fmt.Sprint(entry.User.ID),
+				entry.User.Email,
+				string(entry.User.UserType),
+				fmt.Sprint(entry.Score),
+				strings.Join(findings, "|"),
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the report as indented JSON.
+func (report UserSecurityReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// Security posture of users, see GetUserSecurityReport
+type UserSecurityReport struct {
+	Score         int                         `json:"score"`         // Average score of the users, out of 100
+	Findings      map[UserSecurityFinding]int `json:"findings"`      // Number of users per finding
+	Organizations []OrganizationUserSecurity  `json:"organizations"` // Sorted by organization ID
+}
+
+type OrganizationUserSecurity struct {
+	OrganizationID int                 `json:"organizationId"` // 0 for technicians
+	Score          int                 `json:"score"`          // Average score of the users, out of 100
+	Users          []UserSecurityEntry `json:"users"`          // Worst score first
+}
+
+type UserSecurityEntry struct {
+	User     User                  `json:"user"`
+	Findings []UserSecurityFinding `json:"findings"`
+	Score    int                   `json:"score"` // Out of 100
+}
+
+type UserSecurityFinding string
+
+const (
+	UserFindingNoMFA               UserSecurityFinding = "NO_MFA"
+	UserFindingAdministrator       UserSecurityFinding = "ADMINISTRATOR"
+	UserFindingPermitAllClients    UserSecurityFinding = "PERMIT_ALL_CLIENTS"
+	UserFindingInvitationPending   UserSecurityFinding = "INVITATION_PENDING"
+	UserFindingInvitationExpired   UserSecurityFinding = "INVITATION_EXPIRED"
+	UserFindingDisabledWithDevices UserSecurityFinding = "DISABLED_WITH_DEVICES"
+)
@@ -1,8 +1,11 @@
 package ninjarmm
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 // List all users, can be filtered by user type
@@ -28,6 +31,174 @@ func ListOrganizationUsers(organizationID int) (users []User, err error) {
 	return
 }
 
+// Create an end user for an organization, optionally sending the invitation
+// email
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/createEndUser
+func CreateEndUser(newUser NewEndUser, sendInvitation bool) (user User, err error) {
+	if newUser.OrganizationID == 0 {
+		err = errors.New("organization ID required")
+		return
+	}
+
+	values := url.Values{
+		"sendInvite": {strconv.FormatBool(sendInvitation)},
+	}
+
+	err = request(http.MethodPost, "user/end-users?"+values.Encode(), newUser, &user)
+	return
+}
+
+// Returns an end user
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getEndUser
+func GetEndUser(userID int) (user User, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("user/end-user/%d", userID), nil, &user)
+	return
+}
+
+// Update an end user, only the fields set in `update` are changed
+//
+// Usage:
+//
+//	update := ninjarmm.UserUpdate{}
+//	update.SetPhone("+33 1 23 45 67 89").SetDeviceIDs([]int{12, 13})
+//	err := ninjarmm.UpdateEndUser(userID, update)
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/updateEndUser
+func UpdateEndUser(userID int, update UserUpdate) (err error) {
+	if userID == 0 {
+		err = errors.New("user ID required")
+		return
+	}
+
+	if update.IsEmpty() {
+		err = errors.New("no field set in user update")
+		return
+	}
+
+	err = request(http.MethodPatch, fmt.Sprintf("user/end-user/%d", userID), update, nil)
+	return
+}
+
+// Disable an end user, the user is kept with its devices
+func DisableEndUser(userID int) (err error) {
+	update := UserUpdate{}
+	update.SetEnabled(false)
+	return UpdateEndUser(userID, update)
+}
+
+// Delete an end user
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/deleteEndUser
+func DeleteEndUser(userID int) (err error) {
+	if userID == 0 {
+		err = errors.New("user ID required")
+		return
+	}
+
+	err = request(http.MethodDelete, fmt.Sprintf("user/end-user/%d", userID), nil, nil)
+	return
+}
+
+// Create a technician and send the invitation email
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/createTechnician
+func InviteTechnician(newTechnician NewTechnician) (user User, err error) {
+	values := url.Values{
+		"sendInvite": {"true"},
+	}
+
+	err = request(http.MethodPost, "user/technicians?"+values.Encode(), newTechnician, &user)
+	return
+}
+
+// Returns a technician
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getTechnician
+func GetTechnician(userID int) (user User, err error) {
+	err = request(http.MethodGet, fmt.Sprintf("user/technician/%d", userID), nil, &user)
+	return
+}
+
+// Update a technician (e.g. PermitAllClients and NotifyAllClients
+// permissions), only the fields set in `update` are changed
+func UpdateTechnician(userID int, update UserUpdate) (err error) {
+	if userID == 0 {
+		err = errors.New("user ID required")
+		return
+	}
+
+	if update.IsEmpty() {
+		err = errors.New("no field set in user update")
+		return
+	}
+
+	err = request(http.MethodPatch, fmt.Sprintf("user/technician/%d", userID), update, nil)
+	return
+}
+
+// Send the invitation email again to a user who has not registered yet
+func ResendInvitation(user User) (err error) {
+	if user.InvitationStatus == InvitationStatusRegistered {
+		err = fmt.Errorf("user %d already registered", user.ID)
+		return
+	}
+
+	err = request(http.MethodPost, fmt.Sprintf("user/%d/resend-invite", user.ID), nil, nil)
+	return
+}
+
+// Send the invitation email again to all users of a type (all types if
+// empty) whose invitation is pending or expired. The users reinvited before
+// an error are returned.
+func ResendPendingInvitations(userType UserType) (reinvited []User, err error) {
+	users, err := ListUsers(userType)
+	if err != nil {
+		return
+	}
+
+	for _, user := range users {
+		if user.InvitationStatus != InvitationStatusPending && user.InvitationStatus != InvitationStatusExpired {
+			continue
+		}
+
+		err = ResendInvitation(user)
+		if err != nil {
+			err = fmt.Errorf("error resending invitation to user %d: %w", user.ID, err)
+			return
+		}
+
+		reinvited = append(reinvited, user)
+	}
+
+	return
+}
+
+// List technician and end user roles
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/getUserRoles
+func ListUserRoles() (roles []UserRole, err error) {
+	err = request(http.MethodGet, "user/roles", nil, &roles)
+	return
+}
+
+// Add users to a user role
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/addUsersToRole
+func AddUsersToRole(roleID int, userIDs ...int) (err error) {
+	err = request(http.MethodPatch, fmt.Sprintf("user/role/%d/add-users", roleID), userIDs, nil)
+	return
+}
+
+// Remove users from a user role
+//
+// See https://eu.ninjarmm.com/apidocs-beta/core-resources/operations/removeUsersFromRole
+func RemoveUsersFromRole(roleID int, userIDs ...int) (err error) {
+	err = request(http.MethodPatch, fmt.Sprintf("user/role/%d/remove-users", roleID), userIDs, nil)
+	return
+}
+
 type UserType string
 
 const (
@@ -63,3 +234,94 @@ type User struct {
 	Tags             []string         `json:"tags"`
 	Fields           CustomFields     `json:"fields"`
 }
+
+type NewEndUser struct {
+	Firstname        string `json:"firstname"`
+	Lastname         string `json:"lastname"`
+	Email            string `json:"email"`
+	Phone            string `json:"phone,omitempty"`
+	OrganizationID   int    `json:"organizationId"`
+	FullPortalAccess bool   `json:"fullPortalAccess"` // Access to all the devices of the organization
+	DeviceIDs        []int  `json:"deviceIds,omitempty"`
+}
+
+type NewTechnician struct {
+	Firstname        string `json:"firstname"`
+	Lastname         string `json:"lastname"`
+	Email            string `json:"email"`
+	Phone            string `json:"phone,omitempty"`
+	UserRoleIDs      []int  `json:"userRoleIds,omitempty"`
+	PermitAllClients bool   `json:"permitAllClients"` // Access to all organizations
+	NotifyAllClients bool   `json:"notifyAllClients"` // Notified for all organizations
+}
+
+// Partial update of a user, see UpdateEndUser and UpdateTechnician
+type UserUpdate struct {
+	Firstname        *string `json:"firstname,omitempty"`
+	Lastname         *string `json:"lastname,omitempty"`
+	Phone            *string `json:"phone,omitempty"`
+	Enabled          *bool   `json:"enabled,omitempty"`
+	PermitAllClients *bool   `json:"permitAllClients,omitempty"`
+	NotifyAllClients *bool   `json:"notifyAllClients,omitempty"`
+	DeviceIDs        *[]int  `json:"deviceIds,omitempty"` // An empty list unassigns all devices
+}
+
+// SetFirstname sets the new first name.
+func (update *UserUpdate) SetFirstname(firstname string) *UserUpdate {
+	update.Firstname = &firstname
+	return update
+}
+
+// SetLastname sets the new last name.
+func (update *UserUpdate) SetLastname(lastname string) *UserUpdate {
+	update.Lastname = &lastname
+	return update
+}
+
+// SetPhone sets the new phone number.
+func (update *UserUpdate) SetPhone(phone string) *UserUpdate {
+	update.Phone = &phone
+	return update
+}
+
+// SetEnabled enables or disables the user.
+func (update *UserUpdate) SetEnabled(enabled bool) *UserUpdate {
+	update.Enabled = &enabled
+	return update
+}
+
+// SetPermitAllClients grants or revokes access to all organizations.
+func (update *UserUpdate) SetPermitAllClients(permitAllClients bool) *UserUpdate {
+	update.PermitAllClients = &permitAllClients
+	return update
+}
+
+// SetNotifyAllClients enables or disables notifications for all
+// organizations.
+func (update *UserUpdate) SetNotifyAllClients(notifyAllClients bool) *UserUpdate {
+	update.NotifyAllClients = &notifyAllClients
+	return update
+}
+
+// SetDeviceIDs sets the devices assigned to an end user.
+func (update *UserUpdate) SetDeviceIDs(deviceIDs []int) *UserUpdate {
+	if deviceIDs == nil {
+		deviceIDs = []int{}
+	}
+	update.DeviceIDs = &deviceIDs
+	return update
+}
+
+// IsEmpty returns true if no field is set.
+func (update UserUpdate) IsEmpty() bool {
+	return update.Firstname == nil && update.Lastname == nil && update.Phone == nil && update.Enabled == nil &&
+		update.PermitAllClients == nil && update.NotifyAllClients == nil && update.DeviceIDs == nil
+}
+
+type UserRole struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	SystemRole  bool     `json:"systemRole"`
+	Type        UserType `json:"type"` // 'TECHNICIAN' or 'END_USER'
+}
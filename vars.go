@@ -2,6 +2,7 @@ package ninjarmm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -24,13 +25,12 @@ var (
 
 // Base request used by all other requests
 func request(method, path string, payload interface{}, response interface{}) (err error) {
+	return requestContext(context.Background(), method, path, payload, response)
+}
 
-	// Check if we already have a valid token
-	err = Login()
-	if err != nil {
-		err = fmt.Errorf("error logging in: %w", err)
-		return
-	}
+// Same as request but bound to a context, used by long running helpers
+// which must be cancellable
+func requestContext(ctx context.Context, method, path string, payload interface{}, response interface{}) (err error) {
 
 	buffer := new(bytes.Buffer)
 	if payload != nil {
@@ -41,7 +41,42 @@ func request(method, path string, payload interface{}, response interface{}) (er
 		}
 	}
 
-	req, err := http.NewRequest(method, apiUrl+path, buffer)
+	// Mutations are intercepted in dry-run mode, they are logged and never
+	// sent to the API
+	body := bytes.TrimSpace(buffer.Bytes())
+	intercepted := interceptDryRun(method, path, body, response)
+
+	defer func() {
+		recordAudit(ctx, method, path, body, intercepted, err)
+	}()
+
+	if intercepted {
+		return
+	}
+
+	// Reference data may be served from the cache
+	if lookupCache(method, path, response) {
+		return
+	}
+
+	// Fail fast when the API is known to be unavailable
+	done, err := allowCircuit(path)
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		done(err)
+	}()
+
+	// Check if we already have a valid token
+	err = Login()
+	if err != nil {
+		err = fmt.Errorf("error logging in: %w", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiUrl+path, buffer)
 	if err != nil {
 		err = fmt.Errorf("error creating request: %w", err)
 		return
@@ -61,20 +96,45 @@ func request(method, path string, payload interface{}, response interface{}) (er
 
 	if status := res.StatusCode; status > 299 {
 		body, _ := io.ReadAll(res.Body)
-		err = fmt.Errorf("error bad status code '%d' : %s", status, body)
+		err = &APIError{StatusCode: status, Body: string(body)}
 		return
 	}
 
-	if response != nil {
-		err = json.NewDecoder(res.Body).Decode(response)
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		err = fmt.Errorf("error reading response body: %w", err)
+		return
+	}
+
+	// Empty body (e.g. 204 No Content), nothing to decode
+	if response != nil && len(bytes.TrimSpace(data)) > 0 {
+		err = json.Unmarshal(data, response)
 		if err != nil {
 			err = fmt.Errorf("error decoding response body: %w", err)
+			return
 		}
 	}
 
+	if method == http.MethodGet {
+		storeCache(path, data)
+	} else {
+		invalidateCacheFor(path)
+		invalidateReferencesFor(path)
+	}
+
 	return
 }
 
+// Error returned when the API answers with a bad status code
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("error bad status code '%d' : %s", e.StatusCode, e.Body)
+}
+
 // Base request for multipart/form-data requests
 // func UploadMultipartFile(client *http.Client, uri, key, path string) (*http.Response, error) {
 // 	body, writer := io.Pipe()